@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Roles carried in the roles claim of access tokens, named after the chaincode roles
+const (
+	roleRegistrar = "registrar" // University staff who issue and correct results
+	roleAuditor   = "auditor"   // University staff with read-only access
+	roleStudent   = "student"   // Students
+	roleHR        = "hr"        // Company recruiters
+)
+
+// Key of the authenticated user in the gin context
+const authUserKey = "authUser"
+
+// authConfig selects the token issuer and the claims a user is mapped from
+type authConfig struct {
+	Issuer        string // Issuer URL of the OIDC provider
+	Dev           bool   // Whether the development issuer may be used when Issuer is empty
+	Audience      string // Expected audience of access tokens
+	JWKSURL       string // JWK set of the issuer, discovered from the issuer when empty
+	RolesClaim    string // Claim listing the user's roles, possibly a dotted path
+	IdentityClaim string // Claim naming the enrolled wallet identity of the user
+	DevUsers      string // Users file of the development issuer
+	DevIssuer     string // Issuer URL of the development issuer
+}
+
+// authUser is the caller of an authenticated request
+type authUser struct {
+	Subject  string   `json:"subject"`
+	Identity string   `json:"identity"` // Wallet label transactions are signed with
+	Roles    []string `json:"roles"`
+}
+
+// loadAuthConfig reads the authentication settings from AUTH_* environment variables
+func loadAuthConfig() authConfig {
+	return authConfig{
+		Issuer:        os.Getenv("AUTH_ISSUER"),
+		Dev:           os.Getenv("AUTH_DEV") == "1",
+		Audience:      envOrDefault("AUTH_AUDIENCE", "credential-api"),
+		JWKSURL:       os.Getenv("AUTH_JWKS_URL"),
+		RolesClaim:    envOrDefault("AUTH_ROLES_CLAIM", "roles"),
+		IdentityClaim: envOrDefault("AUTH_IDENTITY_CLAIM", "fabric_identity"),
+		DevUsers:      envOrDefault("AUTH_DEV_USERS", "dev-users.yaml"),
+		DevIssuer:     envOrDefault("AUTH_DEV_ISSUER", "http://localhost:8080"),
+	}
+}
+
+func envOrDefault(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// newAuthenticator builds the token verifier of the configured issuer. Without AUTH_ISSUER the
+// development issuer is started only when AUTH_DEV=1 opts in, and returned so that its routes can
+// be registered; otherwise startup fails rather than accept tokens from a local issuer.
+func newAuthenticator(config authConfig) (*tokenVerifier, *devIssuer, error) {
+	if config.Issuer != "" {
+		verifier := &tokenVerifier{
+			issuer:   config.Issuer,
+			audience: config.Audience,
+			keys:     newJWKSSource(config.Issuer, config.JWKSURL),
+		}
+		return verifier, nil, nil
+	}
+
+	if !config.Dev {
+		return nil, nil, fmt.Errorf("AUTH_ISSUER is not set; set AUTH_DEV=1 to use the development issuer")
+	}
+	issuer, err := newDevIssuer(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start development issuer: %w", err)
+	}
+	log.Printf("AUTH_DEV is set; using the development issuer %s with users from %s", config.DevIssuer, config.DevUsers)
+	verifier := &tokenVerifier{issuer: config.DevIssuer, audience: config.Audience, keys: issuer}
+	return verifier, issuer, nil
+}
+
+// authenticate resolves the bearer token of a request to its user. Requests without a token
+// continue anonymously and are refused by requireRoles; an invalid token is refused here.
+func authenticate(verifier *tokenVerifier, config authConfig) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		header := ctx.GetHeader("Authorization")
+		if header == "" {
+			ctx.Next()
+			return
+		}
+
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization must be a Bearer token"})
+			return
+		}
+		claims, err := verifier.verify(strings.TrimSpace(token))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Only a wallet label enrolled for the user may sign their transactions, never the subject
+		user := &authUser{Roles: claims.stringList(config.RolesClaim)}
+		user.Subject, _ = claims["sub"].(string)
+		user.Identity, _ = claims.lookup(config.IdentityClaim).(string)
+		if user.Identity == "" {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("token names no enrolled wallet identity in claim %q", config.IdentityClaim)})
+			return
+		}
+		ctx.Set(authUserKey, user)
+		ctx.Next()
+	}
+}
+
+// requireRoles admits authenticated users holding at least one of the roles
+func requireRoles(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user := currentUser(ctx)
+		if user == nil {
+			ctx.Header("WWW-Authenticate", "Bearer")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		for _, role := range roles {
+			if hasRole(ctx, role) {
+				ctx.Next()
+				return
+			}
+		}
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("One of the roles %v is required", roles)})
+	}
+}
+
+// currentUser returns the authenticated user of a request, or nil
+func currentUser(ctx *gin.Context) *authUser {
+	value, ok := ctx.Get(authUserKey)
+	if !ok {
+		return nil
+	}
+	user, _ := value.(*authUser)
+	return user
+}
+
+// hasRole reports whether the authenticated user of a request holds a role
+func hasRole(ctx *gin.Context, role string) bool {
+	user := currentUser(ctx)
+	if user == nil {
+		return false
+	}
+	for _, held := range user.Roles {
+		if held == role {
+			return true
+		}
+	}
+	return false
+}
+
+// caller returns the wallet identity that signs the transactions of a request. Routes calling it
+// are guarded by requireRoles, so the user is always set.
+func caller(ctx *gin.Context) string {
+	return currentUser(ctx).Identity
+}
@@ -1,98 +1,118 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"google.golang.org/grpc/codes"
 )
 
-// Submit a transaction synchronously, blocking until it has been committed to the ledger.
-func submitTxnFn(organization string, channelName string, chaincodeName string, contractName string, txnType string, privateData map[string][]byte, txnName string, args ...string) string {
+// Channel and chaincode every transaction of the API is sent to
+const (
+	defaultChannel   = "mychannel"
+	defaultChaincode = "Credential-Verification"
+)
 
-	orgProfile := profile[organization]
-	mspID := orgProfile.MSPID
-	certPath := orgProfile.CertPath
-	keyPath := orgProfile.KeyDirectory
-	tlsCertPath := orgProfile.TLSCertPath
-	gatewayPeer := orgProfile.GatewayPeer
-	peerEndpoint := orgProfile.PeerEndpoint
+// Transactions reading result marks, which are kept in the marks_<MSPID> collection of the issuing
+// institution and so can only be endorsed by peers of its member organizations
+var resultMarksTransactions = map[string]bool{
+	"CreateResult":             true,
+	"ReadResult":               true,
+	"GetAllResults":            true,
+	"MatchResult":              true,
+	"GetMatchingResults":       true,
+	"ApproveVerification":      true,
+	"RejectVerification":       true,
+	"GetVerificationStatement": true,
+	"GetVerifiedMarks":         true,
+}
 
-	// The gRPC client connection should be shared by all Gateway connections to this endpoint
-	clientConnection := newGrpcConnection(tlsCertPath, gatewayPeer, peerEndpoint)
-	defer clientConnection.Close()
+// Member organizations of the marks collection of UniversityMSP, the only institution the API
+// endorses with
+var resultMarksOrganizations = []string{"UniversityMSP", "StudentMSP"}
 
-	id := newIdentity(certPath, mspID)
-	sign := newSign(keyPath)
+// proposalOptions returns the options of a transaction proposal, targeting the peers of the
+// marks collection for transactions that read marks
+func proposalOptions(txnName string, args []string) []client.ProposalOption {
+	options := []client.ProposalOption{client.WithArguments(args...)}
+	if resultMarksTransactions[txnName] {
+		options = append(options, client.WithEndorsingOrganizations(resultMarksOrganizations...))
+	}
+	return options
+}
 
-	// Create a Gateway connection for a specific client identity
-	gw, err := client.Connect(
-		id,
-		client.WithSign(sign),
-		client.WithClientConnection(clientConnection),
-	)
+// evaluateTxn runs a query transaction signed by a wallet identity on a peer of its organization, or
+// of the marks collection for transactions reading marks, without ordering it. Failures are
+// returned as *FabricError.
+func evaluateTxn(user string, contractName string, txnName string, args ...string) ([]byte, error) {
+	contract, err := contractFor(user, contractName, txnName)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	defer gw.Close()
 
-	network := gw.GetNetwork(channelName)
-	contract := network.GetContractWithName(chaincodeName, contractName)
-	fmt.Printf("\n-->Submiting Transaction: %s,\n", txnName)
+	fmt.Printf("\n-->Evaluating Transaction: %s,\n", txnName)
+	result, err := contract.Evaluate(txnName, proposalOptions(txnName, args)...)
+	if err != nil {
+		return nil, newFabricError(txnName, "evaluate", err)
+	}
+	return result, nil
+}
 
-	switch txnType {
-	case "invoke":
-		result, err := contract.SubmitTransaction(txnName, args...)
+// readAuditedTxn runs a read transaction whose chaincode records an access audit for company
+// callers. Writes made while evaluating are discarded, so audited reads are submitted instead.
+func readAuditedTxn(user string, audited bool, contractName string, txnName string, args ...string) ([]byte, error) {
+	if audited {
+		return submitTxn(user, contractName, txnName, args...)
+	}
+	return evaluateTxn(user, contractName, txnName, args...)
+}
 
-		if err != nil {
-			panic(fmt.Errorf("failed to submit transaction: %w", err))
-		}
+// submitTxn submits a transaction synchronously, blocking until it has been committed to the ledger.
+// Failures are returned as *FabricError.
+func submitTxn(user string, contractName string, txnName string, args ...string) ([]byte, error) {
+	return submitPrivateTxn(user, contractName, txnName, nil, args...)
+}
 
-		return fmt.Sprintf("*** Transaction submitted successfully: %s\n", result)
+// submitPrivateTxn submits a transaction carrying private data in its transient map, which is
+// passed to the endorsing peers but not recorded on the ledger
+func submitPrivateTxn(user string, contractName string, txnName string, privateData map[string][]byte, args ...string) ([]byte, error) {
+	contract, err := contractFor(user, contractName, txnName)
+	if err != nil {
+		return nil, err
+	}
 
-	case "query":
-		evaluateResult, err := contract.EvaluateTransaction(txnName, args...)
-		if err != nil {
-			panic(fmt.Errorf("failed to evaluate transaction: %w", err))
-		}
+	fmt.Printf("\n-->Submiting Transaction: %s,\n", txnName)
+	options := proposalOptions(txnName, args)
+	if len(privateData) > 0 {
+		options = append(options, client.WithTransient(privateData))
+	}
+	result, err := contract.Submit(txnName, options...)
+	if err != nil {
+		return nil, newFabricError(txnName, "submit", err)
+	}
+	return result, nil
+}
 
-		var result string
-		if isByteSliceEmpty(evaluateResult) {
-			result = string(evaluateResult)
-		} else {
-			result = formatJSON(evaluateResult)
+// contractFor returns a contract of the chaincode through the Gateway of a wallet identity
+func contractFor(user string, contractName string, txnName string) (*client.Contract, error) {
+	gw, _, err := gateways.User(user)
+	if err != nil {
+		code := codes.Unavailable
+		if errors.Is(err, ErrIdentityNotFound) {
+			// The user has no enrolled identity to sign with
+			code = codes.PermissionDenied
 		}
-
-		// return fmt.Sprintf("*** Result:%s\n", result)
-		return result
-
-	case "private":
-		result, err := contract.Submit(
-			txnName,
-			client.WithArguments(args...),
-			client.WithTransient(privateData),
-		)
-
-		if err != nil {
-			panic(fmt.Errorf("failed to submit transaction: %w", err))
+		return nil, &FabricError{
+			Transaction: txnName,
+			Stage:       "connect",
+			Code:        code.String(),
+			Message:     err.Error(),
+			grpcCode:    code,
+			err:         err,
 		}
-
-		return fmt.Sprintf("*** Transaction committed successfully\n result: %s \n", result)
 	}
 
-	return ""
+	network := gw.GetNetwork(defaultChannel)
+	return network.GetContractWithName(defaultChaincode, contractName), nil
 }
-
-// Format JSON data
-func formatJSON(data []byte) string {
-	var prettyJSON bytes.Buffer
-	if err := json.Indent(&prettyJSON, data, "", "  "); err != nil {
-		panic(fmt.Errorf("failed to parse JSON: %w", err))
-	}
-	return prettyJSON.String()
-}
-
-func isByteSliceEmpty(data []byte) bool {
-	return len(data) == 0
-}
\ No newline at end of file
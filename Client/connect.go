@@ -2,47 +2,52 @@
 package main
 
 import (
+	"crypto"
 	"crypto/x509"
 	"fmt"
 	"os"
 	"path"
+	"time"
 
 	"github.com/hyperledger/fabric-gateway/pkg/identity"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials"
 )
 
-func newGrpcConnection(tlsCertPath string, gatewayPeer string, peerEndpoint string) *grpc.ClientConn {
-	certificate, err := loadCertificate(tlsCertPath)
-	if err != nil {
-		panic(err)
-	}
+// Reconnection backoff applied by gRPC when a peer becomes unreachable
+var peerBackoff = backoff.Config{
+	BaseDelay:  1 * time.Second,
+	Multiplier: 1.6,
+	Jitter:     0.2,
+	MaxDelay:   30 * time.Second,
+}
 
+func newGrpcConnection(certificate *x509.Certificate, gatewayPeer string, peerEndpoint string) (*grpc.ClientConn, error) {
 	certPool := x509.NewCertPool()
 	certPool.AddCert(certificate)
 	transportCredentials := credentials.NewClientTLSFromCert(certPool, gatewayPeer)
 
-	connection, err := grpc.Dial(peerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	connection, err := grpc.Dial(
+		peerEndpoint,
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: peerBackoff, MinConnectTimeout: 5 * time.Second}),
+	)
 	if err != nil {
-		panic(fmt.Errorf("failed to create gRPC connection: %w", err))
+		return nil, fmt.Errorf("failed to create gRPC connection: %w", err)
 	}
 
-	return connection
+	return connection, nil
 }
 
 // newIdentity creates a client identity for this Gateway connection using an X.509 certificate.
-func newIdentity(certPath string, mspID string) *identity.X509Identity {
+func newIdentity(certPath string, mspID string) (*identity.X509Identity, error) {
 	certificate, err := loadCertificate(certPath)
 	if err != nil {
-		panic(err)
-	}
-
-	id, err := identity.NewX509Identity(mspID, certificate)
-	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return id
+	return identity.NewX509Identity(mspID, certificate)
 }
 
 func loadCertificate(filename string) (*x509.Certificate, error) {
@@ -53,27 +58,54 @@ func loadCertificate(filename string) (*x509.Certificate, error) {
 	return identity.CertificateFromPEM(certificatePEM)
 }
 
-// newSign creates a function that generates a digital signature from a message digest using a private key.
-func newSign(keyPath string) identity.Sign {
-	files, err := os.ReadDir(keyPath)
+// newSign creates a function that generates a digital signature from a message digest using the
+// private key of the certificate.
+func newSign(keyPath string, certPath string) (identity.Sign, error) {
+	certificate, err := loadCertificate(certPath)
 	if err != nil {
-		panic(fmt.Errorf("failed to read private key directory: %w", err))
+		return nil, err
 	}
-	privateKeyPEM, err := os.ReadFile(path.Join(keyPath, files[0].Name()))
-
+	privateKey, _, err := findPrivateKey(keyPath, certificate)
 	if err != nil {
-		panic(fmt.Errorf("failed to read private key file: %w", err))
+		return nil, err
 	}
+	return identity.NewPrivateKeySign(privateKey)
+}
 
-	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
+// findPrivateKey returns the key of a keystore directory that belongs to the certificate, with
+// its PEM encoding. Keystores may hold the keys of earlier enrollments next to the current one.
+func findPrivateKey(keyPath string, certificate *x509.Certificate) (crypto.PrivateKey, []byte, error) {
+	files, err := os.ReadDir(keyPath)
 	if err != nil {
-		panic(err)
+		return nil, nil, fmt.Errorf("failed to read private key directory: %w", err)
 	}
 
-	sign, err := identity.NewPrivateKeySign(privateKey)
-	if err != nil {
-		panic(err)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		privateKeyPEM, err := os.ReadFile(path.Join(keyPath, file.Name()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read private key file: %w", err)
+		}
+		privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
+		if err != nil {
+			// Not a key, e.g. a stray file in the keystore
+			continue
+		}
+		if keyMatches(privateKey, certificate) {
+			return privateKey, privateKeyPEM, nil
+		}
 	}
+	return nil, nil, fmt.Errorf("no private key in %s matches the certificate", keyPath)
+}
 
-	return sign
-}
\ No newline at end of file
+// keyMatches reports whether a private key is the key of the certificate
+func keyMatches(privateKey crypto.PrivateKey, certificate *x509.Certificate) bool {
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return false
+	}
+	publicKey, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && publicKey.Equal(certificate.PublicKey)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// Lifetime of the tokens of the development issuer
+const devTokenTTL = 1 * time.Hour
+
+// devIssuer is a local OpenID provider for development. It signs ES256 tokens with a key
+// generated at startup for the users of a file, and publishes discovery and JWK set documents
+// so that other services can verify its tokens like those of a real provider.
+type devIssuer struct {
+	config authConfig
+	key    *ecdsa.PrivateKey
+	keyId  string
+	users  map[string]devUser
+}
+
+// devUser is a user of the development issuer. Passwords are kept in clear text: the file must
+// never hold real credentials.
+type devUser struct {
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	Identity string   `yaml:"identity"` // Wallet label the user signs with
+	Roles    []string `yaml:"roles"`
+}
+
+func newDevIssuer(config authConfig) (*devIssuer, error) {
+	data, err := os.ReadFile(config.DevUsers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+	var file struct {
+		Users []devUser `yaml:"users"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse users file %s: %w", config.DevUsers, err)
+	}
+
+	users := make(map[string]devUser)
+	for _, user := range file.Users {
+		if user.Username == "" || user.Password == "" {
+			return nil, fmt.Errorf("users file %s has a user without username or password", config.DevUsers)
+		}
+		users[user.Username] = user
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	publicKey, err := json.Marshal(devJWK(&key.PublicKey, ""))
+	if err != nil {
+		return nil, err
+	}
+	keyId := sha256.Sum256(publicKey)
+
+	return &devIssuer{
+		config: config,
+		key:    key,
+		keyId:  base64.RawURLEncoding.EncodeToString(keyId[:8]),
+		users:  users,
+	}, nil
+}
+
+// publicKey implements keySource for tokens verified in the same process
+func (d *devIssuer) publicKey(kid string) (crypto.PublicKey, error) {
+	if kid != d.keyId {
+		return nil, fmt.Errorf("unknown token key %q", kid)
+	}
+	return &d.key.PublicKey, nil
+}
+
+// login checks a user's password and issues an access token
+func (d *devIssuer) login(username string, password string) (string, error) {
+	user, ok := d.users[username]
+	if !ok || subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) != 1 {
+		return "", fmt.Errorf("invalid username or password")
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":                  d.config.DevIssuer,
+		"sub":                  user.Username,
+		"aud":                  d.config.Audience,
+		"iat":                  now.Unix(),
+		"nbf":                  now.Unix(),
+		"exp":                  now.Add(devTokenTTL).Unix(),
+		d.config.RolesClaim:    user.Roles,
+		d.config.IdentityClaim: user.Identity,
+	}
+	return d.sign(claims)
+}
+
+// sign encodes claims as a compact ES256 JWT
+func (d *devIssuer) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "typ": "JWT", "kid": d.keyId})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, d.key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	// JWS encodes ES256 signatures as the fixed-size r and s, not ASN.1
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// devJWK encodes a P-256 public key as a JWK
+func devJWK(key *ecdsa.PublicKey, keyId string) jsonWebKey {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return jsonWebKey{
+		Kty: "EC",
+		Kid: keyId,
+		Use: "sig",
+		Alg: "ES256",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(x),
+		Y:   base64.RawURLEncoding.EncodeToString(y),
+	}
+}
+
+// registerRoutes publishes the login, discovery and JWK set endpoints of the issuer
+func (d *devIssuer) registerRoutes(router *gin.Engine) {
+	router.GET("/.well-known/openid-configuration", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"issuer":                                d.config.DevIssuer,
+			"jwks_uri":                              d.config.DevIssuer + "/.well-known/jwks.json",
+			"token_endpoint":                        d.config.DevIssuer + "/api/auth/login",
+			"id_token_signing_alg_values_supported": []string{"ES256"},
+		})
+	})
+
+	router.GET("/.well-known/jwks.json", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"keys": []jsonWebKey{devJWK(&d.key.PublicKey, d.keyId)}})
+	})
+
+	router.POST("/api/auth/login", func(ctx *gin.Context) {
+		var req struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "username and password are required"})
+			return
+		}
+
+		token, err := d.login(req.Username, req.Password)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"access_token": token,
+			"token_type":   "Bearer",
+			"expires_in":   int(devTokenTTL.Seconds()),
+		})
+	})
+}
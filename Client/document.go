@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+// Largest document accepted by the upload endpoints
+const maxDocumentSize = 10 << 20
+
+// hashDocument computes the hex SHA-256 of an uploaded file and determines its MIME type
+func hashDocument(fileHeader *multipart.FileHeader) (string, string, error) {
+	if fileHeader.Size > maxDocumentSize {
+		return "", "", fmt.Errorf("document exceeds %d bytes", maxDocumentSize)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open uploaded document: %w", err)
+	}
+	defer file.Close()
+
+	// Sniff the content type from the first bytes instead of trusting the client header
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", "", fmt.Errorf("failed to read uploaded document: %w", err)
+	}
+	mimeType := http.DetectContentType(head[:n])
+
+	hasher := sha256.New()
+	hasher.Write(head[:n])
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", "", fmt.Errorf("failed to read uploaded document: %w", err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), mimeType, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FabricError is a transaction that failed at the gateway, the peers or the orderer, with the
+// chaincode message and the error reported by each node
+type FabricError struct {
+	Transaction string          `json:"transaction"`              // Chaincode function invoked
+	Stage       string          `json:"stage"`                    // connect, evaluate, endorse, submit or commit
+	TxId        string          `json:"txId,omitempty"`           // Transaction ID, once a proposal was built
+	Code        string          `json:"code"`                     // gRPC status code, or validation code of a failed commit
+	Message     string          `json:"message"`                  // Chaincode message when a peer returned one
+	Chaincode   json.RawMessage `json:"chaincodeError,omitempty"` // Chaincode message, when it is a structured JSON error
+	ErrorCode   string          `json:"errorCode,omitempty"`      // Code of the structured chaincode error, e.g. NOT_FOUND
+	Peers       []PeerError     `json:"peers,omitempty"`          // Errors returned by individual nodes
+
+	grpcCode       codes.Code
+	validationCode peer.TxValidationCode
+	err            error
+}
+
+// PeerError is the error returned by one endorsing peer or ordering node
+type PeerError struct {
+	Address string `json:"address"`
+	MspId   string `json:"mspId"`
+	Message string `json:"message"`
+}
+
+func (e *FabricError) Error() string {
+	if e.TxId != "" {
+		return e.Stage + " of " + e.Transaction + " (" + e.TxId + ") failed: " + e.Message
+	}
+	return e.Stage + " of " + e.Transaction + " failed: " + e.Message
+}
+
+func (e *FabricError) Unwrap() error {
+	return e.err
+}
+
+// Prefix added by peers to the message of a chaincode that returned an error
+var chaincodeResponsePrefix = regexp.MustCompile(`^chaincode response \d+, `)
+
+// newFabricError unwraps the errors of the gateway client: the stage and transaction ID of an
+// EndorseError, SubmitError, CommitStatusError or CommitError, and the per-node details of the
+// gRPC status
+func newFabricError(txnName string, stage string, err error) *FabricError {
+	fabricErr := &FabricError{Transaction: txnName, Stage: stage, err: err}
+
+	var endorseErr *client.EndorseError
+	var submitErr *client.SubmitError
+	var commitStatusErr *client.CommitStatusError
+	var commitErr *client.CommitError
+	switch {
+	case errors.As(err, &endorseErr):
+		fabricErr.Stage = "endorse"
+		fabricErr.TxId = endorseErr.TransactionID
+	case errors.As(err, &submitErr):
+		fabricErr.Stage = "submit"
+		fabricErr.TxId = submitErr.TransactionID
+	case errors.As(err, &commitStatusErr):
+		fabricErr.Stage = "commit"
+		fabricErr.TxId = commitStatusErr.TransactionID
+	case errors.As(err, &commitErr):
+		// The transaction was ordered but invalidated by the peers, e.g. an MVCC read conflict
+		fabricErr.Stage = "commit"
+		fabricErr.TxId = commitErr.TransactionID
+		fabricErr.Code = commitErr.Code.String()
+		fabricErr.Message = commitErr.Error()
+		fabricErr.validationCode = commitErr.Code
+		return fabricErr
+	}
+
+	grpcStatus := status.Convert(err)
+	fabricErr.grpcCode = grpcStatus.Code()
+	fabricErr.Code = grpcStatus.Code().String()
+	fabricErr.Message = grpcStatus.Message()
+	for _, detail := range grpcStatus.Details() {
+		if errorDetail, ok := detail.(*gateway.ErrorDetail); ok {
+			fabricErr.Peers = append(fabricErr.Peers, PeerError{
+				Address: errorDetail.GetAddress(),
+				MspId:   errorDetail.GetMspId(),
+				Message: chaincodeResponsePrefix.ReplaceAllString(errorDetail.GetMessage(), ""),
+			})
+		}
+	}
+
+	// Peers running the same chaincode return the same message; report the first one
+	if len(fabricErr.Peers) > 0 {
+		fabricErr.Message = fabricErr.Peers[0].Message
+	}
+	if strings.HasPrefix(fabricErr.Message, "{") && json.Valid([]byte(fabricErr.Message)) {
+		fabricErr.Chaincode = json.RawMessage(fabricErr.Message)
+		var coded struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(fabricErr.Chaincode, &coded) == nil {
+			fabricErr.ErrorCode = coded.Code
+			if coded.Message != "" {
+				fabricErr.Message = coded.Message
+			}
+		}
+	}
+	return fabricErr
+}
+
+// Codes of structured chaincode errors mapped to HTTP status codes. Chaincode errors without a
+// code are internal failures.
+var chaincodeStatuses = map[string]int{
+	"OFFER_PURGED":     http.StatusGone,
+	"INVALID_OFFER":    http.StatusBadRequest,
+	"INVALID_REVISION": http.StatusBadRequest,
+	"INVALID_ARGUMENT": http.StatusBadRequest,
+	"NOT_FOUND":        http.StatusNotFound,
+	"FORBIDDEN":        http.StatusForbidden,
+	"CONFLICT":         http.StatusConflict,
+}
+
+// HTTPStatus maps the failure to the status code of the API response
+func (e *FabricError) HTTPStatus() int {
+	switch e.validationCode {
+	case peer.TxValidationCode_VALID:
+	case peer.TxValidationCode_MVCC_READ_CONFLICT, peer.TxValidationCode_PHANTOM_READ_CONFLICT:
+		return http.StatusConflict
+	default:
+		return http.StatusBadGateway
+	}
+
+	switch e.grpcCode {
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	}
+
+	if status, ok := chaincodeStatuses[e.ErrorCode]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// respondError writes a failed transaction as a JSON error response
+func respondError(ctx *gin.Context, err error) {
+	log.Printf("Error: %v", err)
+
+	var fabricErr *FabricError
+	if errors.As(err, &fabricErr) {
+		ctx.JSON(fabricErr.HTTPStatus(), gin.H{"error": fabricErr.Message, "details": fabricErr})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
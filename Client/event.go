@@ -3,43 +3,18 @@ package main
 import (
 	"context"
 	"fmt"
-	"time"
+	"log"
 
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 )
 
 func blockEventListener(organization string, channelName string) {
 
-	orgProfile := profile[organization]
-	mspID := orgProfile.MSPID
-	certPath := orgProfile.CertPath
-	keyPath := orgProfile.KeyDirectory
-	tlsCertPath := orgProfile.TLSCertPath
-	gatewayPeer := orgProfile.GatewayPeer
-	peerEndpoint := orgProfile.PeerEndpoint
-
-	// The gRPC client connection should be shared by all Gateway connections to this endpoint
-	clientConnection := newGrpcConnection(tlsCertPath, gatewayPeer, peerEndpoint)
-	defer clientConnection.Close()
-
-	id := newIdentity(certPath, mspID)
-	sign := newSign(keyPath)
-
-	// Create a Gateway connection for a specific client identity
-	gw, err := client.Connect(
-		id,
-		client.WithSign(sign),
-		client.WithClientConnection(clientConnection),
-		// Default timeouts for different gRPC calls
-		client.WithEvaluateTimeout(5*time.Second),
-		client.WithEndorseTimeout(15*time.Second),
-		client.WithSubmitTimeout(5*time.Second),
-		client.WithCommitStatusTimeout(1*time.Minute),
-	)
+	gw, err := gateways.Gateway(organization)
 	if err != nil {
-		panic(err)
+		log.Printf("Event listening for %s not started: %v", organization, err)
+		return
 	}
-	defer gw.Close()
 
 	network := gw.GetNetwork(channelName)
 
@@ -51,7 +26,8 @@ func blockEventListener(organization string, channelName string) {
 
 	events, err := network.BlockEvents(ctx, client.WithStartBlock(2))
 	if err != nil {
-		panic(fmt.Errorf("failed to start Block event listening: %w", err))
+		log.Printf("Failed to start Block event listening: %v", err)
+		return
 	}
 
 	for event := range events {
@@ -62,36 +38,11 @@ func blockEventListener(organization string, channelName string) {
 
 func chaincodeEventListener(organization string, channelName string, chaincodeName string) {
 
-	orgProfile := profile[organization]
-	mspID := orgProfile.MSPID
-	certPath := orgProfile.CertPath
-	keyPath := orgProfile.KeyDirectory
-	tlsCertPath := orgProfile.TLSCertPath
-	gatewayPeer := orgProfile.GatewayPeer
-	peerEndpoint := orgProfile.PeerEndpoint
-
-	// The gRPC client connection should be shared by all Gateway connections to this endpoint
-	clientConnection := newGrpcConnection(tlsCertPath, gatewayPeer, peerEndpoint)
-	defer clientConnection.Close()
-
-	id := newIdentity(certPath, mspID)
-	sign := newSign(keyPath)
-
-	// Create a Gateway connection for a specific client identity
-	gw, err := client.Connect(
-		id,
-		client.WithSign(sign),
-		client.WithClientConnection(clientConnection),
-		// Default timeouts for different gRPC calls
-		client.WithEvaluateTimeout(5*time.Second),
-		client.WithEndorseTimeout(15*time.Second),
-		client.WithSubmitTimeout(5*time.Second),
-		client.WithCommitStatusTimeout(1*time.Minute),
-	)
+	gw, err := gateways.Gateway(organization)
 	if err != nil {
-		panic(err)
+		log.Printf("Event listening for %s not started: %v", organization, err)
+		return
 	}
-	defer gw.Close()
 
 	network := gw.GetNetwork(channelName)
 
@@ -103,7 +54,8 @@ func chaincodeEventListener(organization string, channelName string, chaincodeNa
 
 	events, err := network.ChaincodeEvents(ctx, chaincodeName)
 	if err != nil {
-		panic(fmt.Errorf("failed to start Chaincode event listening: %w", err))
+		log.Printf("Failed to start Chaincode event listening: %v", err)
+		return
 	}
 
 	for event := range events {
@@ -114,36 +66,11 @@ func chaincodeEventListener(organization string, channelName string, chaincodeNa
 
 func pvtBlockEventListener(organization string, channelName string) {
 
-	orgProfile := profile[organization]
-	mspID := orgProfile.MSPID
-	certPath := orgProfile.CertPath
-	keyPath := orgProfile.KeyDirectory
-	tlsCertPath := orgProfile.TLSCertPath
-	gatewayPeer := orgProfile.GatewayPeer
-	peerEndpoint := orgProfile.PeerEndpoint
-
-	// The gRPC client connection should be shared by all Gateway connections to this endpoint
-	clientConnection := newGrpcConnection(tlsCertPath, gatewayPeer, peerEndpoint)
-	defer clientConnection.Close()
-
-	id := newIdentity(certPath, mspID)
-	sign := newSign(keyPath)
-
-	// Create a Gateway connection for a specific client identity
-	gw, err := client.Connect(
-		id,
-		client.WithSign(sign),
-		client.WithClientConnection(clientConnection),
-		// Default timeouts for different gRPC calls
-		client.WithEvaluateTimeout(5*time.Second),
-		client.WithEndorseTimeout(15*time.Second),
-		client.WithSubmitTimeout(5*time.Second),
-		client.WithCommitStatusTimeout(1*time.Minute),
-	)
+	gw, err := gateways.Gateway(organization)
 	if err != nil {
-		panic(err)
+		log.Printf("Event listening for %s not started: %v", organization, err)
+		return
 	}
-	defer gw.Close()
 
 	network := gw.GetNetwork(channelName)
 
@@ -154,7 +81,8 @@ func pvtBlockEventListener(organization string, channelName string) {
 
 	events, err := network.BlockAndPrivateDataEvents(ctx, client.WithStartBlock(1))
 	if err != nil {
-		panic(fmt.Errorf("failed to start Block event listening: %w", err))
+		log.Printf("Failed to start Block event listening: %v", err)
+		return
 	}
 	// .GetHeader().GetNumber()
 
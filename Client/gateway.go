@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// Delays between attempts to build an organization's connection when its profile cannot be loaded.
+// Once built, reconnection to a restarted peer is left to gRPC and peerBackoff.
+const (
+	minRebuildDelay = 1 * time.Second
+	maxRebuildDelay = 30 * time.Second
+)
+
+// gateways holds the connections shared by every request, created in main
+var gateways *gatewayPool
+
+// OrgHealth is the connection status of one organization
+type OrgHealth struct {
+	Org        string    `json:"org"`
+	Endpoint   string    `json:"endpoint"`
+	State      string    `json:"state"` // gRPC connectivity state, e.g. READY or TRANSIENT_FAILURE
+	Healthy    bool      `json:"healthy"`
+	Since      time.Time `json:"since"` // When the state last changed
+	Reconnects int       `json:"reconnects"`
+	LastError  string    `json:"lastError,omitempty"`
+}
+
+// gatewayPool keeps one gRPC connection and Gateway per organization profile for the
+// lifetime of the process, and a Gateway per end-user identity on top of those connections.
+// Gateways are safe for concurrent use.
+type gatewayPool struct {
+	connections map[string]*orgConnection
+	cancel      context.CancelFunc
+	identities  wallet
+
+	usersMu sync.Mutex
+	users   map[string]*userGateway
+}
+
+// userGateway is the Gateway of one wallet identity
+type userGateway struct {
+	gateway     *client.Gateway
+	sign        identity.Sign
+	fingerprint string // Wallet fingerprint of the identity the Gateway signs with
+}
+
+// orgConnection is the shared connection of one organization and its observed health
+type orgConnection struct {
+	org    string
+	config Config
+
+	mu         sync.RWMutex
+	conn       *grpc.ClientConn
+	gateway    *client.Gateway
+	state      connectivity.State
+	since      time.Time
+	wasReady   bool
+	reconnects int
+	lastError  string
+}
+
+// newGatewayPool connects to every profile and starts monitoring the connections. Profiles that
+// cannot be loaded are retried in the background instead of failing startup.
+func newGatewayPool(profiles map[string]Config, identities wallet) *gatewayPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := &gatewayPool{
+		connections: make(map[string]*orgConnection),
+		cancel:      cancel,
+		identities:  identities,
+		users:       make(map[string]*userGateway),
+	}
+
+	for org, config := range profiles {
+		connection := &orgConnection{org: org, config: config, state: connectivity.Idle, since: time.Now()}
+		if err := connection.connect(); err != nil {
+			log.Printf("Connection for %s not available yet: %v", org, err)
+		}
+		pool.connections[org] = connection
+		go connection.monitor(ctx)
+	}
+
+	return pool
+}
+
+// Gateway returns the shared Gateway of an organization
+func (p *gatewayPool) Gateway(org string) (*client.Gateway, error) {
+	connection, ok := p.connections[org]
+	if !ok {
+		return nil, fmt.Errorf("no profile for organization %q", org)
+	}
+
+	connection.mu.RLock()
+	defer connection.mu.RUnlock()
+	if connection.gateway == nil {
+		return nil, fmt.Errorf("no connection for organization %s: %s", org, connection.lastError)
+	}
+	return connection.gateway, nil
+}
+
+// User returns the Gateway and signer of a wallet identity, connected through the shared
+// connection of an organization with the identity's MSP. Only identities enrolled in the wallet
+// are used; the identities of the connection profiles never sign end-user requests. The wallet is
+// checked on every lookup: a cached Gateway is dropped once its identity is removed from the
+// wallet, and rebuilt once the identity is replaced.
+func (p *gatewayPool) User(label string) (*client.Gateway, identity.Sign, error) {
+	p.usersMu.Lock()
+	defer p.usersMu.Unlock()
+
+	fingerprint, err := p.identities.Fingerprint(label)
+	if err != nil {
+		p.dropUser(label)
+		return nil, nil, err
+	}
+	if user, ok := p.users[label]; ok {
+		if user.fingerprint == fingerprint {
+			return user.gateway, user.sign, nil
+		}
+		log.Printf("Identity %s was replaced in the wallet; reconnecting", label)
+		p.dropUser(label)
+	}
+
+	walletId, err := p.identities.Get(label)
+	if err != nil {
+		return nil, nil, err
+	}
+	id, sign, err := walletId.x509Identity()
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := p.connectionForMSP(id.MspID())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gw, err := client.Connect(id, gatewayOptions(sign, conn)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.users[label] = &userGateway{gateway: gw, sign: sign, fingerprint: fingerprint}
+	return gw, sign, nil
+}
+
+// dropUser closes and forgets the cached Gateway of a wallet identity. Callers hold usersMu.
+func (p *gatewayPool) dropUser(label string) {
+	if user, ok := p.users[label]; ok {
+		user.gateway.Close()
+		delete(p.users, label)
+	}
+}
+
+// connectionForMSP returns an established connection of an organization with the MSP
+func (p *gatewayPool) connectionForMSP(mspID string) (*grpc.ClientConn, error) {
+	orgs := make([]string, 0, len(p.connections))
+	for org, connection := range p.connections {
+		if connection.config.MSPID == mspID {
+			orgs = append(orgs, org)
+		}
+	}
+	if len(orgs) == 0 {
+		return nil, fmt.Errorf("no organization profile for MSP %s", mspID)
+	}
+	sort.Strings(orgs)
+
+	for _, org := range orgs {
+		connection := p.connections[org]
+		connection.mu.RLock()
+		conn := connection.conn
+		connection.mu.RUnlock()
+		if conn != nil {
+			return conn, nil
+		}
+	}
+	return nil, fmt.Errorf("no connection for MSP %s yet", mspID)
+}
+
+// Health reports the status of every organization, ordered by name
+func (p *gatewayPool) Health() []OrgHealth {
+	health := make([]OrgHealth, 0, len(p.connections))
+	for _, connection := range p.connections {
+		connection.mu.RLock()
+		health = append(health, OrgHealth{
+			Org:        connection.org,
+			Endpoint:   connection.config.PeerEndpoint,
+			State:      connection.state.String(),
+			Healthy:    connection.gateway != nil && connection.state == connectivity.Ready,
+			Since:      connection.since,
+			Reconnects: connection.reconnects,
+			LastError:  connection.lastError,
+		})
+		connection.mu.RUnlock()
+	}
+
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].Org < health[j].Org
+	})
+	return health
+}
+
+// Close stops monitoring and closes every Gateway and connection
+func (p *gatewayPool) Close() {
+	p.cancel()
+	p.usersMu.Lock()
+	for _, user := range p.users {
+		user.gateway.Close()
+	}
+	p.usersMu.Unlock()
+
+	for _, connection := range p.connections {
+		connection.mu.Lock()
+		if connection.gateway != nil {
+			connection.gateway.Close()
+		}
+		if connection.conn != nil {
+			connection.conn.Close()
+		}
+		connection.mu.Unlock()
+	}
+}
+
+// connect loads the profile's identity and opens its connection and Gateway
+func (c *orgConnection) connect() error {
+	id, err := newIdentity(c.config.CertPath, c.config.MSPID)
+	if err != nil {
+		c.fail(err)
+		return err
+	}
+	sign, err := newSign(c.config.KeyDirectory, c.config.CertPath)
+	if err != nil {
+		c.fail(err)
+		return err
+	}
+	tlsCertificate, err := c.config.tlsCertificate()
+	if err != nil {
+		c.fail(err)
+		return err
+	}
+	conn, err := newGrpcConnection(tlsCertificate, c.config.GatewayPeer, c.config.PeerEndpoint)
+	if err != nil {
+		c.fail(err)
+		return err
+	}
+
+	gw, err := client.Connect(id, gatewayOptions(sign, conn)...)
+	if err != nil {
+		conn.Close()
+		c.fail(err)
+		return err
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.gateway = gw
+	c.lastError = ""
+	c.mu.Unlock()
+	return nil
+}
+
+// gatewayOptions configures a Gateway signing with an identity over a shared connection. Closing
+// such a Gateway leaves the connection open.
+func gatewayOptions(sign identity.Sign, conn *grpc.ClientConn) []client.ConnectOption {
+	return []client.ConnectOption{
+		client.WithSign(sign),
+		client.WithClientConnection(conn),
+		// Default timeouts for different gRPC calls
+		client.WithEvaluateTimeout(5 * time.Second),
+		client.WithEndorseTimeout(15 * time.Second),
+		client.WithSubmitTimeout(5 * time.Second),
+		client.WithCommitStatusTimeout(1 * time.Minute),
+	}
+}
+
+// monitor builds the connection until it succeeds, then follows its state, asking gRPC to
+// reconnect whenever the peer drops
+func (c *orgConnection) monitor(ctx context.Context) {
+	delay := minRebuildDelay
+	for {
+		c.mu.RLock()
+		conn := c.conn
+		c.mu.RUnlock()
+
+		if conn == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if err := c.connect(); err != nil {
+				log.Printf("Connection for %s failed, retrying in %v: %v", c.org, delay, err)
+				delay *= 2
+				if delay > maxRebuildDelay {
+					delay = maxRebuildDelay
+				}
+			}
+			continue
+		}
+
+		state := conn.GetState()
+		c.observe(state)
+		if state == connectivity.Idle || state == connectivity.TransientFailure {
+			conn.Connect()
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
+}
+
+// observe records a connectivity state, counting recoveries after the connection was lost
+func (c *orgConnection) observe(state connectivity.State) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if state == c.state {
+		return
+	}
+
+	if state == connectivity.Ready {
+		if c.wasReady {
+			c.reconnects++
+			log.Printf("Connection for %s restored", c.org)
+		}
+		c.wasReady = true
+		c.lastError = ""
+	} else if state == connectivity.TransientFailure {
+		c.lastError = fmt.Sprintf("peer %s unreachable", c.config.PeerEndpoint)
+		log.Printf("Connection for %s lost: %s", c.org, c.lastError)
+	}
+	c.state = state
+	c.since = time.Now()
+}
+
+// fail records why the connection could not be built
+func (c *orgConnection) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastError = err.Error()
+	c.since = time.Now()
+}
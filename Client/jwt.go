@@ -0,0 +1,324 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Clock skew tolerated when checking the time claims of a token
+const tokenLeeway = 1 * time.Minute
+
+// Shortest interval between two downloads of an issuer's keys, bounding the requests caused by
+// tokens with unknown key IDs
+const jwksRefreshInterval = 1 * time.Minute
+
+// tokenClaims are the claims of a verified token
+type tokenClaims map[string]interface{}
+
+// keySource provides the public keys an issuer signs tokens with, by key ID
+type keySource interface {
+	publicKey(kid string) (crypto.PublicKey, error)
+}
+
+// tokenVerifier checks JWT bearer tokens signed with RS256 or ES256, as issued by OIDC providers
+type tokenVerifier struct {
+	issuer   string
+	audience string // Expected "aud"; not checked when empty
+	keys     keySource
+}
+
+// verify checks the signature, issuer, audience and validity period of a compact JWT
+func (v *tokenVerifier) verify(token string) (tokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %w", err)
+	}
+
+	key, err := v.keys.publicKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(header.Alg, key, digest[:], signature); err != nil {
+		return nil, err
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// verifySignature checks a JWS signature over a SHA-256 digest. Only asymmetric algorithms are
+// accepted, so a token cannot be signed with the public key as an HMAC secret.
+func verifySignature(alg string, key crypto.PublicKey, digest []byte, signature []byte) error {
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("token key is not an RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest, signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve != elliptic.P256() {
+			return errors.New("token key is not a P-256 key")
+		}
+		if len(signature) != 64 {
+			return errors.New("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return errors.New("invalid token signature")
+		}
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	return nil
+}
+
+// checkClaims checks the issuer, audience, expiry and not-before claims
+func (v *tokenVerifier) checkClaims(claims tokenClaims) error {
+	if issuer, _ := claims["iss"].(string); issuer != v.issuer {
+		return fmt.Errorf("token issued by %q, expected %q", issuer, v.issuer)
+	}
+	if v.audience != "" && !claims.hasAudience(v.audience) {
+		return fmt.Errorf("token is not intended for audience %q", v.audience)
+	}
+
+	now := time.Now()
+	expiry, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(int64(expiry), 0).Add(tokenLeeway)) {
+		return errors.New("token has expired")
+	}
+	if notBefore, ok := claims["nbf"].(float64); ok && now.Add(tokenLeeway).Before(time.Unix(int64(notBefore), 0)) {
+		return errors.New("token is not valid yet")
+	}
+	return nil
+}
+
+// hasAudience reports whether "aud", a string or an array of strings, contains the audience
+func (c tokenClaims) hasAudience(audience string) bool {
+	switch aud := c["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, entry := range aud {
+			if entry == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookup returns the claim at a dotted path, e.g. "realm_access.roles" for Keycloak roles
+func (c tokenClaims) lookup(path string) interface{} {
+	var value interface{} = map[string]interface{}(c)
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+// stringList returns a claim holding a string or an array of strings
+func (c tokenClaims) stringList(path string) []string {
+	switch value := c.lookup(path).(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, entry := range value {
+			if s, ok := entry.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// jsonWebKey is a public key of a JWK set
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// publicKey decodes an RSA or P-256 key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid P-256 key coordinates")
+		}
+		// Reject points that are not on the curve before using them
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("invalid P-256 key: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// jwksSource downloads the JWK set of an OIDC issuer, locating it through the issuer's discovery
+// document unless its URL is configured, and refreshes it when a token names an unknown key
+type jwksSource struct {
+	issuer  string
+	jwksURL string
+	client  *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func newJWKSSource(issuer string, jwksURL string) *jwksSource {
+	return &jwksSource{
+		issuer:  issuer,
+		jwksURL: jwksURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		keys:    make(map[string]crypto.PublicKey),
+	}
+}
+
+func (s *jwksSource) publicKey(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+
+	if time.Since(s.fetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown token key %q", kid)
+	}
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown token key %q", kid)
+}
+
+// refresh replaces the cached keys with the issuer's current JWK set
+func (s *jwksSource) refresh() error {
+	s.fetchedAt = time.Now()
+	if s.jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := s.getJSON(strings.TrimSuffix(s.issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return fmt.Errorf("failed to discover issuer keys: %w", err)
+		}
+		if discovery.JWKSURI == "" {
+			return fmt.Errorf("issuer %s does not publish a jwks_uri", s.issuer)
+		}
+		s.jwksURL = discovery.JWKSURI
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := s.getJSON(s.jwksURL, &set); err != nil {
+		return fmt.Errorf("failed to fetch issuer keys: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped, not fatal
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	s.keys = keys
+	return nil
+}
+
+func (s *jwksSource) getJSON(url string, v interface{}) error {
+	response, err := s.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", url, response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(v)
+}
@@ -1,52 +1,72 @@
 package main
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"log"
+	"os"
+	"strconv"
 	"sync"
 	"github.com/gin-gonic/gin"
 )
 
 type Result struct {
-	ResultId      string `json:"resultId"`
-	StudentId     string `json:"studentId"`
-	TotalMarks    string `json:"totalMarks"`
-	ObtainedMarks string `json:"obtainedMarks"`
-	Percentage    string `json:"percentage"`
-	Status        string `json:"status"`
+	AssetType     string            `json:"assetType,omitempty"`
+	ResultId      string            `json:"resultId"`
+	StudentId     string            `json:"studentId"`
+	IssuerMsp     string            `json:"issuerMsp,omitempty"`
+	IssuerName    string            `json:"issuerName,omitempty"`
+	TotalMarks    float64           `json:"totalMarks"`
+	ObtainedMarks float64           `json:"obtainedMarks"`
+	Percentage    float64           `json:"percentage"`
+	Status        string            `json:"status"`
+	Revoked       bool              `json:"revoked"`
+	Revocation    map[string]string `json:"revocation,omitempty"`
 }
 
 type Offer struct {
-	OfferId       string `json:"offerId"`
-	StudentId     string `json:"studentId"`
-	AssetType     string `json:"assetType"`
-	Ctc           string `json:"ctc"`
-	DateOfJoining string `json:"dateOfJoining"`
-	DateOfRelease string `json:"dateOfRelease"`
-	Name          string `json:"name"`
-	Email         string `json:"email"`
-	CompanyName   string `json:"companyName"`
+	OfferId       string          `json:"offerId"`
+	StudentId     string          `json:"studentId"`
+	ResultId      string          `json:"resultId,omitempty"`
+	AssetType     string          `json:"assetType"`
+	Ctc           json.RawMessage `json:"ctc"`
+	DateOfJoining string          `json:"dateOfJoining"`
+	DateOfRelease string          `json:"dateOfRelease"`
+	Name          string          `json:"name"`
+	Email         string          `json:"email"`
+	CompanyName   string          `json:"companyName"`
+	ValidUntil    string          `json:"validUntil,omitempty"`
+	Status        string          `json:"status,omitempty"`
+	Purged        bool            `json:"purged,omitempty"`
+	Warning       string          `json:"warning,omitempty"`
 }
 
-type ResultData struct {
-	AssetType	  string `json:"AssetType"`
-	ResultId      string `json:"ResultId"`
-	StudentId     string `json:"StudentId"`
-	TotalMarks 	  string `json:"TotalMarks"`
-	ObtainedMarks string `json:"ObtainedMarks"`
-	Percentage    string `json:"Percentage"`
-	Status        string `json:"Status"`
+type OfferRevision struct {
+	Ctc           json.RawMessage `json:"ctc,omitempty"`
+	DateOfJoining string          `json:"dateOfJoining,omitempty"`
+	Note          string          `json:"note"`
 }
 
 type OfferData struct {
-	OfferId     string `json:"OfferId"`
-	StudentId   string `json:"StudentId"`
-	AssetType   string `json:"AssetType"`
-	Status      string `json:"Status"`
-	CompanyName string `json:"CompanyName"`
-	Ctc         string `json:"Ctc"`
-	Name        string `json:"Name"`
-	Email       string `json:"Email"`
+	OfferId     string          `json:"OfferId"`
+	StudentId   string          `json:"StudentId"`
+	AssetType   string          `json:"AssetType"`
+	Status      string          `json:"Status"`
+	CompanyName string          `json:"CompanyName"`
+	Ctc         json.RawMessage `json:"Ctc"`
+	Name        string          `json:"Name"`
+	Email       string          `json:"Email"`
+}
+
+type VerificationRequest struct {
+	RequestId string `json:"requestId"`
+	ResultId  string `json:"resultId"`
+	Purpose   string `json:"purpose"`
+}
+
+type VerificationDecision struct {
+	Decision string `json:"decision"`
+	Comments string `json:"comments"`
 }
 
 type Match struct {
@@ -55,10 +75,10 @@ type Match struct {
 }
 
 type ResultHistory struct {
-	Record    *ResultData `json:"record"`
-	TxId      string      `json:"txId"`
-	Timestamp string      `json:"timestamp"`
-	IsDelete  bool        `json:"isDelete"`
+	Record    *Result `json:"record"`
+	TxId      string  `json:"txId"`
+	Timestamp string  `json:"timestamp"`
+	IsDelete  bool    `json:"isDelete"`
 }
 
 // Placeholder for ChaincodeEventListener function
@@ -76,7 +96,43 @@ func getEvents() []string {
 
 
 func main() {
+	identities, err := openWallet()
+	if err != nil {
+		log.Fatalf("Failed to open wallet: %v", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "wallet" {
+		if err := runWalletCommand(identities, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	profiles, err := loadProfiles(profilesPath())
+	if err != nil {
+		log.Fatalf("Invalid connection profiles: %v", err)
+	}
+
+	// One gRPC connection and Gateway per organization, shared by every request
+	gateways = newGatewayPool(profiles, identities)
+	defer gateways.Close()
+
+	authConfig := loadAuthConfig()
+	verifier, issuer, err := newAuthenticator(authConfig)
+	if err != nil {
+		log.Fatalf("Invalid authentication settings: %v", err)
+	}
+
 	router := gin.Default()
+	router.MaxMultipartMemory = maxDocumentSize
+	// Transactions are signed with the wallet identity of the authenticated user
+	router.Use(authenticate(verifier, authConfig))
+	if issuer != nil {
+		issuer.registerRoutes(router)
+	}
+
+	// Role requirements shared by several routes
+	anyRole := requireRoles(roleRegistrar, roleAuditor, roleStudent, roleHR)
+	offerParties := requireRoles(roleHR, roleStudent)
 
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -88,15 +144,29 @@ func main() {
 		})
 	})
 
+	// Connection status of each organization; 503 while any of them is down
+	router.GET("/api/health", func(ctx *gin.Context) {
+		health := gateways.Health()
+		status := 200
+		for _, org := range health {
+			if !org.Healthy {
+				status = 503
+			}
+		}
+		ctx.JSON(status, gin.H{"organizations": health})
+	})
+
 	// Result-related routes
-	router.GET("/api/results", func(ctx *gin.Context) {
-		queryParams := make(map[string][]byte)
-		queryParams["AssetType"] = []byte("Result")
-		result := submitTxnFn("university", "mychannel", "Credential-Verification", "ResultContract", "query", queryParams, "GetAllResults")
+	router.GET("/api/results", anyRole, func(ctx *gin.Context) {
+		result, err := readAuditedTxn(caller(ctx), hasRole(ctx, roleHR), "ResultContract", "GetAllResults")
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
 
-		var results []ResultData
+		var results []Result
 		if len(result) > 0 {
-			if err := json.Unmarshal([]byte(result), &results); err != nil {
+			if err := json.Unmarshal(result, &results); err != nil {
 				log.Println("Error:", err)
 				ctx.JSON(500, gin.H{"error": "Failed to parse results"})
 				return
@@ -111,32 +181,47 @@ func main() {
 		ctx.JSON(200, results)
 	})
 
-	router.POST("/api/result", func(ctx *gin.Context) {
+	router.POST("/api/result", requireRoles(roleRegistrar), func(ctx *gin.Context) {
 		var req Result
 		if err := ctx.BindJSON(&req); err != nil {
 			ctx.JSON(400, gin.H{"message": "Bad request"})
 			return
 		}
 
-		log.Printf("Result received: %+v", req)
-		res := submitTxnFn("university", "mychannel", "Credential-Verification", "ResultContract", "invoke", make(map[string][]byte), "CreateResult",
-			req.ResultId, req.StudentId, req.TotalMarks, req.ObtainedMarks, req.Percentage, req.Status)
+		log.Printf("Creating result %s for student %s", req.ResultId, req.StudentId)
+		// The marks are kept private, salted from a secret only the endorsing peers see
+		salt := make([]byte, 32)
+		if _, err := rand.Read(salt); err != nil {
+			ctx.JSON(500, gin.H{"error": "Failed to generate the result salt"})
+			return
+		}
+		// Percentage and status are computed by the chaincode from the marks
+		res, err := submitPrivateTxn(caller(ctx), "ResultContract", "CreateResult", map[string][]byte{"resultSalt": salt},
+			req.ResultId, req.StudentId, strconv.FormatFloat(req.TotalMarks, 'f', -1, 64), strconv.FormatFloat(req.ObtainedMarks, 'f', -1, 64))
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
 
-		ctx.JSON(200, res)
+		ctx.JSON(200, string(res))
 	})
 
-	router.GET("/api/result/:id", func(ctx *gin.Context) {
+	router.GET("/api/result/:id", anyRole, func(ctx *gin.Context) {
 		resultId := ctx.Param("id")
 		if resultId == "" {
 			ctx.JSON(400, gin.H{"error": "ResultId is required"})
 			return
 		}
 
-		result := submitTxnFn("university", "mychannel", "Credential-Verification", "ResultContract", "query", make(map[string][]byte), "ReadResult", resultId)
+		result, err := readAuditedTxn(caller(ctx), hasRole(ctx, roleHR), "ResultContract", "ReadResult", resultId)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
 
 		var singleResult Result
 		if len(result) > 0 {
-			if err := json.Unmarshal([]byte(result), &singleResult); err != nil {
+			if err := json.Unmarshal(result, &singleResult); err != nil {
 				log.Printf("Error unmarshalling result: %v", err)
 				ctx.JSON(500, gin.H{"error": "Failed to parse result"})
 				return
@@ -146,66 +231,152 @@ func main() {
 		ctx.JSON(200, gin.H{"singleData": singleResult})
 	})
 
+	// Document anchoring routes; the SHA-256 is computed here so the file never reaches the ledger
+	router.POST("/api/result/:id/document", requireRoles(roleRegistrar), func(ctx *gin.Context) {
+		resultId := ctx.Param("id")
+		fileHeader, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "A multipart file field named 'file' is required"})
+			return
+		}
+
+		hash, mimeType, err := hashDocument(fileHeader)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		log.Printf("Anchoring document %s (%s) to result %s", hash, mimeType, resultId)
+		res, err := submitTxn(caller(ctx), "ResultContract", "AnchorDocument", resultId, hash, mimeType)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		ctx.JSON(200, gin.H{"hash": hash, "mimeType": mimeType, "response": string(res)})
+	})
+
+	router.POST("/api/document/verify", anyRole, func(ctx *gin.Context) {
+		fileHeader, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "A multipart file field named 'file' is required"})
+			return
+		}
+
+		hash, _, err := hashDocument(fileHeader)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := evaluateTxn(caller(ctx), "ResultContract", "VerifyDocument", hash)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+
+		var verification map[string]interface{}
+		if err := json.Unmarshal(result, &verification); err != nil {
+			log.Printf("Error unmarshalling verification: %v", err)
+			ctx.JSON(500, gin.H{"error": "Failed to parse verification"})
+			return
+		}
+
+		ctx.JSON(200, verification)
+	})
+
 	// Offer-related routes
-	router.POST("/api/offer", func(ctx *gin.Context) {
+	router.POST("/api/offer", requireRoles(roleHR), func(ctx *gin.Context) {
 		var req Offer
 		if err := ctx.ShouldBindJSON(&req); err != nil {
 			ctx.JSON(400, gin.H{"message": "Invalid request format"})
 			return
 		}
 
-		// Set AssetType to "Offer"
-		req.AssetType = "Offer"
-
 		if req.OfferId == "" || req.StudentId == "" {
 			ctx.JSON(400, gin.H{"message": "OfferId and StudentId are required"})
 			return
 		}
 
 		privateData := map[string][]byte{
-			"ctc":           []byte(req.Ctc),
+			"ctc":           req.Ctc,
 			"dateOfJoining": []byte(req.DateOfJoining),
 			"dateOfRelease": []byte(req.DateOfRelease),
 			"name":          []byte(req.Name),
 			"email":         []byte(req.Email),
 			"companyName":   []byte(req.CompanyName),
-			"assetType":     []byte(req.AssetType), // Ensure AssetType is included
+		}
+		if req.ValidUntil != "" {
+			privateData["validUntil"] = []byte(req.ValidUntil)
+		}
+		// The nonce salts the offer's public hashes; the student reads it back with the offer
+		privateData["offerNonce"] = make([]byte, 32)
+		if _, err := rand.Read(privateData["offerNonce"]); err != nil {
+			ctx.JSON(500, gin.H{"error": "Failed to generate the offer nonce"})
+			return
+		}
+
+		res, err := submitPrivateTxn(caller(ctx), "OfferContract", "CreateOffer", privateData, req.OfferId, req.StudentId, req.ResultId)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		ctx.JSON(200, gin.H{"response": string(res)})
+	})
+
+	// Check an offer JSON, as returned by GET /api/offer/:id, against its public commitment
+	router.POST("/api/offer/verify", anyRole, func(ctx *gin.Context) {
+		body, err := ctx.GetRawData()
+		if err != nil || !json.Valid(body) {
+			ctx.JSON(400, gin.H{"error": "The request body must be the offer JSON"})
+			return
+		}
+
+		result, err := evaluateTxn(caller(ctx), "OfferContract", "VerifyOfferCommitment", string(body))
+		if err != nil {
+			respondError(ctx, err)
+			return
 		}
 
-		log.Printf("Creating offer with data: %+v", privateData)
-		res := submitTxnFn("company", "mychannel", "Credential-Verification", "OfferContract", "private", privateData, "CreateOffer", req.OfferId, req.StudentId)
-		ctx.JSON(200, gin.H{"response": res})
+		var verification map[string]interface{}
+		if err := json.Unmarshal(result, &verification); err != nil {
+			log.Printf("Error unmarshalling verification: %v", err)
+			ctx.JSON(500, gin.H{"error": "Failed to parse verification"})
+			return
+		}
+
+		ctx.JSON(200, verification)
 	})
 
-	router.GET("/api/offer/:id", func(ctx *gin.Context) {
+	router.GET("/api/offer/:id", offerParties, func(ctx *gin.Context) {
 		offerId := ctx.Param("id")
-		result := submitTxnFn("company", "mychannel", "Credential-Verification", "OfferContract", "query", nil, "ReadOffer", offerId)
+		result, err := evaluateTxn(caller(ctx), "OfferContract", "ReadOffer", offerId)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
 
 		var offer Offer
 		if len(result) > 0 {
-			if err := json.Unmarshal([]byte(result), &offer); err != nil {
+			if err := json.Unmarshal(result, &offer); err != nil {
 				log.Printf("Error unmarshalling offer: %v", err)
 				ctx.JSON(500, gin.H{"error": "Failed to parse offer"})
 				return
 			}
 		}
 
-		// New check: Ensure the student can only see their own offer
-		studentId := ctx.DefaultQuery("studentId", "") // Assuming student ID is passed as a query parameter
-		if studentId != offer.StudentId {
-			ctx.JSON(403, gin.H{"error": "You are not authorized to view this offer"})
-			return
-		}
-
 		ctx.JSON(200, gin.H{"offer": offer})
 	})
 
-	router.GET("/api/offers", func(ctx *gin.Context) {
-		result := submitTxnFn("company", "mychannel", "Credential-Verification", "OfferContract", "query", nil, "GetAllOffers")
+	router.GET("/api/offers", offerParties, func(ctx *gin.Context) {
+		result, err := evaluateTxn(caller(ctx), "OfferContract", "GetAllOffers")
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
 
 		var offers []OfferData
 		if len(result) > 0 {
-			if err := json.Unmarshal([]byte(result), &offers); err != nil {
+			if err := json.Unmarshal(result, &offers); err != nil {
 				log.Printf("Error parsing offers: %v", err)
 				ctx.JSON(500, gin.H{"error": "Failed to parse offers"})
 				return
@@ -219,8 +390,203 @@ func main() {
 		ctx.JSON(200, offers)
 	})
 
+	// Propose revised terms for an offer; the new ctc and dateOfJoining travel as transient data
+	router.POST("/api/offer/:id/revision", offerParties, func(ctx *gin.Context) {
+		var req OfferRevision
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(400, gin.H{"message": "Invalid request format"})
+			return
+		}
+
+		privateData := map[string][]byte{}
+		if len(req.Ctc) > 0 {
+			privateData["ctc"] = req.Ctc
+		}
+		if req.DateOfJoining != "" {
+			privateData["dateOfJoining"] = []byte(req.DateOfJoining)
+		}
+
+		res, err := submitPrivateTxn(caller(ctx), "OfferContract", "ProposeRevision", privateData, ctx.Param("id"), req.Note)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		ctx.JSON(200, gin.H{"response": string(res)})
+	})
+
+	router.POST("/api/offer/:id/revision/:version/accept", offerParties, func(ctx *gin.Context) {
+		res, err := submitTxn(caller(ctx), "OfferContract", "AcceptRevision", ctx.Param("id"), ctx.Param("version"))
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		ctx.JSON(200, gin.H{"response": string(res)})
+	})
+
+	router.GET("/api/offer/:id/negotiation", offerParties, func(ctx *gin.Context) {
+		result, err := evaluateTxn(caller(ctx), "OfferContract", "GetNegotiationThread", ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+
+		var thread []map[string]interface{}
+		if len(result) > 0 {
+			if err := json.Unmarshal(result, &thread); err != nil {
+				log.Printf("Error parsing negotiation thread: %v", err)
+				ctx.JSON(500, gin.H{"error": "Failed to parse negotiation thread"})
+				return
+			}
+		}
+		ctx.JSON(200, thread)
+	})
+
+	// Expire overdue offers and record offers whose terms were purged after blockToLive
+	router.POST("/api/offers/expire", requireRoles(roleHR), func(ctx *gin.Context) {
+		res, err := submitTxn(caller(ctx), "OfferContract", "ExpireOffers")
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		ctx.JSON(200, gin.H{"response": string(res)})
+	})
+
+	// Offers addressed to a student, or justified by a result, visible to the company
+	router.GET("/api/offers/student/:id", offerParties, func(ctx *gin.Context) {
+		result, err := evaluateTxn(caller(ctx), "OfferContract", "GetOffersByStudent", ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+
+		var offers []Offer
+		if len(result) > 0 {
+			if err := json.Unmarshal(result, &offers); err != nil {
+				log.Printf("Error parsing offers: %v", err)
+				ctx.JSON(500, gin.H{"error": "Failed to parse offers"})
+				return
+			}
+		}
+		ctx.JSON(200, offers)
+	})
+
+	router.GET("/api/offers/result/:id", offerParties, func(ctx *gin.Context) {
+		result, err := evaluateTxn(caller(ctx), "OfferContract", "GetOffersByResult", ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+
+		var offers []Offer
+		if len(result) > 0 {
+			if err := json.Unmarshal(result, &offers); err != nil {
+				log.Printf("Error parsing offers: %v", err)
+				ctx.JSON(500, gin.H{"error": "Failed to parse offers"})
+				return
+			}
+		}
+		ctx.JSON(200, offers)
+	})
+
+	// Background verification requests from the company to the issuing university
+	router.POST("/api/verification", requireRoles(roleHR), func(ctx *gin.Context) {
+		var req VerificationRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil || req.RequestId == "" || req.ResultId == "" {
+			ctx.JSON(400, gin.H{"message": "RequestId and ResultId are required"})
+			return
+		}
+
+		res, err := submitTxn(caller(ctx), "VerificationContract", "RequestVerification", req.RequestId, req.ResultId, req.Purpose)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		ctx.JSON(200, gin.H{"response": string(res)})
+	})
+
+	router.GET("/api/verification/:id", anyRole, func(ctx *gin.Context) {
+		result, err := evaluateTxn(caller(ctx), "VerificationContract", "ReadVerificationRequest", ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+
+		var request map[string]interface{}
+		if err := json.Unmarshal(result, &request); err != nil {
+			log.Printf("Error parsing verification request: %v", err)
+			ctx.JSON(500, gin.H{"error": "Failed to parse verification request"})
+			return
+		}
+		ctx.JSON(200, request)
+	})
+
+	// The marks confirmed by an approval are read from the university's private collection and the
+	// read is recorded against the student's consent, so the transaction is submitted
+	router.GET("/api/verification/:id/marks", requireRoles(roleHR), func(ctx *gin.Context) {
+		result, err := submitTxn(caller(ctx), "VerificationContract", "GetVerifiedMarks", ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+
+		var marks map[string]interface{}
+		if err := json.Unmarshal(result, &marks); err != nil {
+			log.Printf("Error parsing verified marks: %v", err)
+			ctx.JSON(500, gin.H{"error": "Failed to parse verified marks"})
+			return
+		}
+		ctx.JSON(200, marks)
+	})
+
+	router.GET("/api/verifications/pending", anyRole, func(ctx *gin.Context) {
+		result, err := evaluateTxn(caller(ctx), "VerificationContract", "GetPendingVerifications")
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+
+		var requests []map[string]interface{}
+		if len(result) > 0 {
+			if err := json.Unmarshal(result, &requests); err != nil {
+				log.Printf("Error parsing verification requests: %v", err)
+				ctx.JSON(500, gin.H{"error": "Failed to parse verification requests"})
+				return
+			}
+		}
+		ctx.JSON(200, requests)
+	})
+
+	// The university signs the statement of its decision before submitting it
+	router.POST("/api/verification/:id/decision", requireRoles(roleRegistrar), func(ctx *gin.Context) {
+		var req VerificationDecision
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(400, gin.H{"message": "Invalid request format"})
+			return
+		}
+
+		txnName := map[string]string{"APPROVED": "ApproveVerification", "REJECTED": "RejectVerification"}[req.Decision]
+		if txnName == "" {
+			ctx.JSON(400, gin.H{"message": "Decision must be APPROVED or REJECTED"})
+			return
+		}
+
+		requestId := ctx.Param("id")
+		signature, err := signVerificationStatement(caller(ctx), requestId, req.Decision, req.Comments)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+
+		res, err := submitTxn(caller(ctx), "VerificationContract", txnName, requestId, req.Comments, signature)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		ctx.JSON(200, gin.H{"response": string(res)})
+	})
+
 	// Matching and Events
-	router.POST("/api/result/match-offer", func(ctx *gin.Context) {
+	router.POST("/api/result/match-offer", requireRoles(roleHR), func(ctx *gin.Context) {
 		var req Match
 		if err := ctx.BindJSON(&req); err != nil {
 			ctx.JSON(400, gin.H{"message": "Bad request"})
@@ -228,12 +594,45 @@ func main() {
 		}
 
 		log.Printf("Match request: %+v", req)
-		submitTxnFn("university", "mychannel", "Credential-Verification", "ResultContract", "invoke", make(map[string][]byte), "MatchOffer", req.ResultId, req.OfferId)
+		res, err := submitTxn(caller(ctx), "OfferContract", "MatchResult", req.OfferId, req.ResultId)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+
+		ctx.JSON(200, gin.H{"match": req, "response": string(res)})
+	})
 
-		ctx.JSON(200, req)
+	// Candidate results ranked against the eligibility policy attached to an offer
+	router.GET("/api/offer/:id/matches", requireRoles(roleHR), func(ctx *gin.Context) {
+		// Submitted so that the consent access audit of each matched result is recorded
+		result, err := submitTxn(caller(ctx), "OfferContract", "GetMatchingResults", ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+
+		var matches []map[string]interface{}
+		if len(result) > 0 {
+			if err := json.Unmarshal(result, &matches); err != nil {
+				log.Printf("Error parsing matches: %v", err)
+				ctx.JSON(500, gin.H{"error": "Failed to parse matches"})
+				return
+			}
+		}
+		ctx.JSON(200, matches)
+	})
+
+	router.POST("/api/offer/:id/policy/:policyId", requireRoles(roleHR), func(ctx *gin.Context) {
+		res, err := submitTxn(caller(ctx), "OfferContract", "SetOfferPolicy", ctx.Param("id"), ctx.Param("policyId"))
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		ctx.JSON(200, gin.H{"response": string(res)})
 	})
 
-	router.GET("/api/events", func(ctx *gin.Context) {
+	router.GET("/api/events", anyRole, func(ctx *gin.Context) {
 		result := getEvents()
 		ctx.JSON(200, gin.H{"events": result})
 	})
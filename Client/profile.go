@@ -1,52 +1,394 @@
 package main
 
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"gopkg.in/yaml.v3"
+)
+
+// Profile file read when FABRIC_PROFILES is not set
+const defaultProfilesFile = "profiles.yaml"
+
 // Config represents the configuration for a role.
 type Config struct {
 	CertPath     string `json:"certPath"`
 	KeyDirectory string `json:"keyPath"`
 	TLSCertPath  string `json:"tlsCertPath"`
+	TLSCertPEM   string `json:"-"` // TLS CA given inline by a connection profile, used instead of TLSCertPath
 	PeerEndpoint string `json:"peerEndpoint"`
 	GatewayPeer  string `json:"gatewayPeer"`
 	MSPID        string `json:"mspID"`
+	MSPDir       string `json:"mspDir"` // MSP directory of the organization, whose CAs must have issued the certificate
+}
+
+// profileFile is the YAML or JSON file listing the organizations the API connects to
+type profileFile struct {
+	Organizations map[string]orgProfile `yaml:"organizations"`
+}
+
+// orgProfile is the peer and users of one organization. The connection fields may instead be
+// taken from a Fabric common connection profile.
+type orgProfile struct {
+	MSPID             string        `yaml:"mspId"`
+	MSPDir            string        `yaml:"mspDir"`
+	PeerEndpoint      string        `yaml:"peerEndpoint"`
+	GatewayPeer       string        `yaml:"gatewayPeer"`
+	TLSCertPath       string        `yaml:"tlsCertPath"`
+	ConnectionProfile string        `yaml:"connectionProfile"`
+	Users             []userProfile `yaml:"users"`
+
+	tlsCertPEM string // TLS CA given inline by the connection profile
+}
+
+// userProfile is one identity of an organization
+type userProfile struct {
+	Name         string `yaml:"name"`
+	CertPath     string `yaml:"certPath"`
+	KeyDirectory string `yaml:"keyPath"`
+}
+
+// commonConnectionProfile holds the parts of a Fabric common connection profile used by the API
+type commonConnectionProfile struct {
+	Client struct {
+		Organization string `yaml:"organization"`
+	} `yaml:"client"`
+	Organizations map[string]struct {
+		MSPID string   `yaml:"mspid"`
+		Peers []string `yaml:"peers"`
+	} `yaml:"organizations"`
+	Peers map[string]struct {
+		URL        string `yaml:"url"`
+		TLSCACerts struct {
+			Path string `yaml:"path"`
+			PEM  string `yaml:"pem"`
+		} `yaml:"tlsCACerts"`
+		GRPCOptions map[string]interface{} `yaml:"grpcOptions"`
+	} `yaml:"peers"`
+}
+
+// profilesPath returns the profile file named by FABRIC_PROFILES, or the default one
+func profilesPath() string {
+	if file := os.Getenv("FABRIC_PROFILES"); file != "" {
+		return file
+	}
+	return defaultProfilesFile
+}
+
+// loadProfiles reads the profile file, applies environment overrides and validates every profile.
+// The first user of an organization is registered under the organization's name, later ones
+// under "<org>/<user>". Relative paths in the file are resolved against its directory.
+func loadProfiles(file string) (map[string]Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile file: %w", err)
+	}
+	// JSON documents are valid YAML, so both formats are read the same way
+	var profiles profileFile
+	if err := yaml.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse profile file %s: %w", file, err)
+	}
+	if len(profiles.Organizations) == 0 {
+		return nil, fmt.Errorf("profile file %s defines no organizations", file)
+	}
+
+	baseDir := filepath.Dir(file)
+	configs := make(map[string]Config)
+	for org, orgConfig := range profiles.Organizations {
+		orgConfig.resolve(baseDir)
+		if orgConfig.ConnectionProfile != "" {
+			err := orgConfig.applyConnectionProfile()
+			if err != nil {
+				return nil, fmt.Errorf("organization %s: %w", org, err)
+			}
+		}
+		orgConfig.applyEnv(envPrefix(org))
+		if len(orgConfig.Users) == 0 {
+			// Credentials may come from the environment alone
+			orgConfig.Users = []userProfile{{}}
+		}
+
+		for i, user := range orgConfig.Users {
+			name := org
+			if i > 0 {
+				if user.Name == "" {
+					return nil, fmt.Errorf("organization %s: user %d has no name", org, i+1)
+				}
+				name = org + "/" + user.Name
+			}
+			if i == 0 {
+				user.applyEnv(envPrefix(org))
+			}
+			if user.Name != "" {
+				user.applyEnv(envPrefix(org + "_" + user.Name))
+			}
+			if _, exists := configs[name]; exists {
+				return nil, fmt.Errorf("profile %s is defined twice", name)
+			}
+
+			configs[name] = Config{
+				CertPath:     user.CertPath,
+				KeyDirectory: user.KeyDirectory,
+				TLSCertPath:  orgConfig.TLSCertPath,
+				TLSCertPEM:   orgConfig.tlsCertPEM,
+				PeerEndpoint: orgConfig.PeerEndpoint,
+				GatewayPeer:  orgConfig.GatewayPeer,
+				MSPID:        orgConfig.MSPID,
+				MSPDir:       orgConfig.MSPDir,
+			}
+		}
+	}
+
+	if err := validateProfiles(configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// resolve makes the paths of the organization and its users relative to the profile file
+func (o *orgProfile) resolve(baseDir string) {
+	o.MSPDir = resolvePath(baseDir, o.MSPDir)
+	o.TLSCertPath = resolvePath(baseDir, o.TLSCertPath)
+	o.ConnectionProfile = resolvePath(baseDir, o.ConnectionProfile)
+	for i := range o.Users {
+		o.Users[i].CertPath = resolvePath(baseDir, o.Users[i].CertPath)
+		o.Users[i].KeyDirectory = resolvePath(baseDir, o.Users[i].KeyDirectory)
+	}
+}
+
+func resolvePath(baseDir string, file string) string {
+	if file == "" || filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(baseDir, file)
+}
+
+// applyConnectionProfile fills the connection fields left empty from the first peer of the
+// client organization of a common connection profile
+func (o *orgProfile) applyConnectionProfile() error {
+	data, err := os.ReadFile(o.ConnectionProfile)
+	if err != nil {
+		return fmt.Errorf("failed to read connection profile: %w", err)
+	}
+	var ccp commonConnectionProfile
+	if err := yaml.Unmarshal(data, &ccp); err != nil {
+		return fmt.Errorf("failed to parse connection profile %s: %w", o.ConnectionProfile, err)
+	}
+
+	orgName := ccp.Client.Organization
+	if orgName == "" && len(ccp.Organizations) == 1 {
+		for name := range ccp.Organizations {
+			orgName = name
+		}
+	}
+	ccpOrg, ok := ccp.Organizations[orgName]
+	if !ok {
+		return fmt.Errorf("connection profile %s does not define the client organization %q", o.ConnectionProfile, orgName)
+	}
+	if len(ccpOrg.Peers) == 0 {
+		return fmt.Errorf("connection profile %s lists no peers for %s", o.ConnectionProfile, orgName)
+	}
+	peerName := ccpOrg.Peers[0]
+	ccpPeer, ok := ccp.Peers[peerName]
+	if !ok {
+		return fmt.Errorf("connection profile %s does not define peer %s", o.ConnectionProfile, peerName)
+	}
+
+	if o.MSPID == "" {
+		o.MSPID = ccpOrg.MSPID
+	}
+	if o.PeerEndpoint == "" {
+		o.PeerEndpoint = strings.TrimPrefix(strings.TrimPrefix(ccpPeer.URL, "grpcs://"), "grpc://")
+	}
+	if o.GatewayPeer == "" {
+		o.GatewayPeer = peerName
+		if override, ok := ccpPeer.GRPCOptions["ssl-target-name-override"].(string); ok && override != "" {
+			o.GatewayPeer = override
+		}
+	}
+	if o.TLSCertPath == "" {
+		if ccpPeer.TLSCACerts.PEM != "" {
+			o.tlsCertPEM = ccpPeer.TLSCACerts.PEM
+		} else {
+			o.TLSCertPath = resolvePath(filepath.Dir(o.ConnectionProfile), ccpPeer.TLSCACerts.Path)
+		}
+	}
+	return nil
+}
+
+// Characters of profile names that cannot appear in environment variable names
+var envUnsafe = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// envPrefix returns the prefix of the variables overriding a profile, e.g. FABRIC_COMPANY_
+func envPrefix(name string) string {
+	return "FABRIC_" + envUnsafe.ReplaceAllString(strings.ToUpper(name), "_") + "_"
+}
+
+// applyEnv overrides the connection fields of an organization from FABRIC_<ORG>_MSPID,
+// _MSP_DIR, _PEER_ENDPOINT, _GATEWAY_PEER and _TLS_CERT_PATH
+func (o *orgProfile) applyEnv(prefix string) {
+	overrideFromEnv(&o.MSPID, prefix+"MSPID")
+	overrideFromEnv(&o.MSPDir, prefix+"MSP_DIR")
+	overrideFromEnv(&o.PeerEndpoint, prefix+"PEER_ENDPOINT")
+	overrideFromEnv(&o.GatewayPeer, prefix+"GATEWAY_PEER")
+	if overrideFromEnv(&o.TLSCertPath, prefix+"TLS_CERT_PATH") {
+		o.tlsCertPEM = ""
+	}
+}
+
+// applyEnv overrides the credentials of a user from FABRIC_<ORG>_<USER>_CERT_PATH and _KEY_PATH.
+// The first user of an organization also reads FABRIC_<ORG>_CERT_PATH and _KEY_PATH.
+func (u *userProfile) applyEnv(prefix string) {
+	overrideFromEnv(&u.CertPath, prefix+"CERT_PATH")
+	overrideFromEnv(&u.KeyDirectory, prefix+"KEY_PATH")
+}
+
+func overrideFromEnv(field *string, name string) bool {
+	value, ok := os.LookupEnv(name)
+	if ok && value != "" {
+		*field = value
+		return true
+	}
+	return false
+}
+
+// validateProfiles checks every profile before any connection is made, reporting all problems
+// at once: missing fields and files, certificates not issued by the CAs of their MSP or by another
+// organization than the declared MSP's, keystores without the key of their certificate, and MSP
+// IDs and MSP directories claimed by more than one organization
+func validateProfiles(configs map[string]Config) error {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []error
+	mspDirs := make(map[string]string)
+	mspOwners := make(map[string]string)
+	dirMSPIDs := make(map[string]string)
+	for _, name := range names {
+		config := configs[name]
+		if err := config.validate(); err != nil {
+			problems = append(problems, fmt.Errorf("profile %s: %w", name, err))
+		}
+
+		if config.MSPID == "" || config.MSPDir == "" {
+			continue
+		}
+		if dir, seen := mspDirs[config.MSPID]; seen && filepath.Clean(dir) != filepath.Clean(config.MSPDir) {
+			problems = append(problems, fmt.Errorf("profile %s: MSP ID %s is already used by profile %s with MSP directory %s", name, config.MSPID, mspOwners[config.MSPID], dir))
+			continue
+		}
+		if mspID, seen := dirMSPIDs[filepath.Clean(config.MSPDir)]; seen && mspID != config.MSPID {
+			problems = append(problems, fmt.Errorf("profile %s: MSP directory %s is already declared as %s", name, config.MSPDir, mspID))
+			continue
+		}
+		mspDirs[config.MSPID] = config.MSPDir
+		mspOwners[config.MSPID] = name
+		dirMSPIDs[filepath.Clean(config.MSPDir)] = config.MSPID
+	}
+
+	// A certificate whose CA also belongs to another organization's MSP was not issued by the
+	// organization it is declared under
+	for _, name := range names {
+		config := configs[name]
+		certificate, err := loadCertificate(config.CertPath)
+		if err != nil {
+			continue
+		}
+		for mspID, dir := range mspDirs {
+			if mspID != config.MSPID && verifyMSPIssued(certificate, dir) == nil {
+				problems = append(problems, fmt.Errorf("profile %s: certificate %s is declared as %s but was issued by a CA of %s", name, config.CertPath, config.MSPID, mspID))
+			}
+		}
+	}
+	return errors.Join(problems...)
 }
 
-// Create a Profile map
-var profile = map[string]Config{
-
-	"university": {
-		CertPath:     "../Network/organizations/peerOrganizations/university.cred.com/users/User1@university.cred.com/msp/signcerts/cert.pem",
-		KeyDirectory: "../Network/organizations/peerOrganizations/university.cred.com/users/User1@university.cred.com/msp/keystore/",
-		TLSCertPath:  "../Network/organizations/peerOrganizations/university.cred.com/peers/peer0.university.cred.com/tls/ca.crt",
-		PeerEndpoint: "localhost:7051",
-		GatewayPeer:  "peer0.university.cred.com",
-		MSPID:        "UniversityMSP",
-	},
-
-	"student": {
-		CertPath:     "../Network/organizations/peerOrganizations/student.cred.com/users/User1@student.cred.com/msp/signcerts/cert.pem",
-		KeyDirectory: "../Network/organizations/peerOrganizations/student.cred.com/users/User1@student.cred.com/msp/keystore/",
-		TLSCertPath:  "../Network/organizations/peerOrganizations/student.cred.com/peers/peer0.student.cred.com/tls/ca.crt",
-		PeerEndpoint: "localhost:9051",
-		GatewayPeer:  "peer0.student.cred.com",
-		MSPID:        "StudentMSP",
-	},
-
-	"company": {
-		CertPath:     "../Network/organizations/peerOrganizations/company.cred.com/users/User1@company.cred.com/msp/signcerts/cert.pem",
-		KeyDirectory: "../Network/organizations/peerOrganizations/company.cred.com/users/User1@company.cred.com/msp/keystore/",
-		TLSCertPath:  "../Network/organizations/peerOrganizations/company.cred.com/peers/peer0.company.cred.com/tls/ca.crt",
-		PeerEndpoint: "localhost:11051",
-		GatewayPeer:  "peer0.company.cred.com",
-		MSPID:        "StudentMSP",
-	},
-
-	"university2": {
-		CertPath:     "../Network/organizations/peerOrganizations/university.cred.com/users/User2@university.cred.com/msp/signcerts/cert.pem",
-		KeyDirectory: "../Network/organizations/peerOrganizations/university.cred.com/users/User2@university.cred.com/msp/keystore/",
-		TLSCertPath:  "../Network/organizations/peerOrganizations/university.cred.com/peers/peer0.university.cred.com/tls/ca.crt",
-		PeerEndpoint: "localhost:7051",
-		GatewayPeer:  "peer0.university.cred.com",
-		MSPID:        "UniversityMSP",
-	},
-
-}
\ No newline at end of file
+// validate checks that the profile is complete and that its files load and belong together
+func (c Config) validate() error {
+	var missing []string
+	for field, value := range map[string]string{"mspId": c.MSPID, "peerEndpoint": c.PeerEndpoint, "gatewayPeer": c.GatewayPeer, "certPath": c.CertPath, "keyPath": c.KeyDirectory, "mspDir": c.MSPDir} {
+		if value == "" {
+			missing = append(missing, field)
+		}
+	}
+	if c.TLSCertPath == "" && c.TLSCertPEM == "" {
+		missing = append(missing, "tlsCertPath")
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+
+	if _, err := c.tlsCertificate(); err != nil {
+		return fmt.Errorf("TLS CA certificate: %w", err)
+	}
+	certificate, err := loadCertificate(c.CertPath)
+	if err != nil {
+		return err
+	}
+	if err := verifyMSPIssued(certificate, c.MSPDir); err != nil {
+		return fmt.Errorf("certificate %s does not belong to %s: %w", c.CertPath, c.MSPID, err)
+	}
+	if _, _, err := findPrivateKey(c.KeyDirectory, certificate); err != nil {
+		return err
+	}
+	return nil
+}
+
+// tlsCertificate loads the TLS CA certificate of the gateway peer
+func (c Config) tlsCertificate() (*x509.Certificate, error) {
+	if c.TLSCertPEM != "" {
+		return identity.CertificateFromPEM([]byte(c.TLSCertPEM))
+	}
+	return loadCertificate(c.TLSCertPath)
+}
+
+// verifyMSPIssued checks that the certificate was issued by a CA of the MSP directory
+func verifyMSPIssued(certificate *x509.Certificate, mspDir string) error {
+	roots, err := loadCertPool(filepath.Join(mspDir, "cacerts"))
+	if err != nil {
+		return err
+	}
+	intermediates, err := loadCertPool(filepath.Join(mspDir, "intermediatecerts"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	_, err = certificate.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+// loadCertPool reads every certificate of an MSP subdirectory
+func loadCertPool(dir string) (*x509.CertPool, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA directory: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		certificate, err := loadCertificate(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		pool.AddCert(certificate)
+	}
+	return pool, nil
+}
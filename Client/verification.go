@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// signVerificationStatement fetches the statement of a decision from the chaincode and signs its
+// digest with the key of the user's wallet identity, returning the base64 signature
+func signVerificationStatement(user string, requestId string, decision string, comments string) (string, error) {
+	result, err := evaluateTxn(user, "VerificationContract", "GetVerificationStatement", requestId, decision, comments)
+	if err != nil {
+		return "", err
+	}
+
+	var statement struct {
+		Digest string `json:"digest"`
+	}
+	if err := json.Unmarshal(result, &statement); err != nil {
+		return "", fmt.Errorf("failed to parse verification statement: %w", err)
+	}
+	digest, err := hex.DecodeString(statement.Digest)
+	if err != nil {
+		return "", fmt.Errorf("invalid statement digest: %w", err)
+	}
+
+	_, sign, err := gateways.User(user)
+	if err != nil {
+		return "", err
+	}
+	signature, err := sign(digest)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign verification statement: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
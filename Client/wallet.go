@@ -0,0 +1,413 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Wallet directory read when FABRIC_WALLET is not set
+const defaultWalletDir = "wallet"
+
+// scrypt cost parameters of the encrypted wallet
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// ErrIdentityNotFound is returned for a label that has no identity in the wallet
+var ErrIdentityNotFound = errors.New("identity not found in wallet")
+
+// wallet stores the X.509 identities of users by label
+type wallet interface {
+	Put(label string, id *walletIdentity) error
+	Get(label string) (*walletIdentity, error)
+	Fingerprint(label string) (string, error)
+	List() ([]string, error)
+	Remove(label string) error
+}
+
+// walletIdentity is an enrolled X.509 identity, in the format of the Fabric SDK file system
+// wallets
+type walletIdentity struct {
+	Version     int    `json:"version"`
+	MSPID       string `json:"mspId"`
+	Type        string `json:"type"`
+	Credentials struct {
+		Certificate string `json:"certificate"`
+		PrivateKey  string `json:"privateKey"`
+	} `json:"credentials"`
+}
+
+// newWalletIdentity builds a wallet identity from PEM credentials, checking that they belong together
+func newWalletIdentity(mspID string, certificatePEM []byte, privateKeyPEM []byte) (*walletIdentity, error) {
+	walletId := &walletIdentity{Version: 1, MSPID: mspID, Type: "X.509"}
+	walletId.Credentials.Certificate = string(certificatePEM)
+	walletId.Credentials.PrivateKey = string(privateKeyPEM)
+
+	if _, _, err := walletId.x509Identity(); err != nil {
+		return nil, err
+	}
+	return walletId, nil
+}
+
+// x509Identity returns the Gateway identity and signer of a wallet identity
+func (w *walletIdentity) x509Identity() (*identity.X509Identity, identity.Sign, error) {
+	if w.Type != "X.509" {
+		return nil, nil, fmt.Errorf("unsupported identity type %q", w.Type)
+	}
+	if w.MSPID == "" {
+		return nil, nil, errors.New("identity has no MSP ID")
+	}
+	certificate, err := identity.CertificateFromPEM([]byte(w.Credentials.Certificate))
+	if err != nil {
+		return nil, nil, err
+	}
+	id, err := identity.NewX509Identity(w.MSPID, certificate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := identity.PrivateKeyFromPEM([]byte(w.Credentials.PrivateKey))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !keyMatches(privateKey, certificate) {
+		return nil, nil, errors.New("private key does not match certificate")
+	}
+	sign, err := identity.NewPrivateKeySign(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return id, sign, nil
+}
+
+// openWallet opens the wallet directory named by FABRIC_WALLET, encrypted with
+// FABRIC_WALLET_PASSPHRASE when it is set
+func openWallet() (wallet, error) {
+	dir := envOrDefault("FABRIC_WALLET", defaultWalletDir)
+	if passphrase := os.Getenv("FABRIC_WALLET_PASSPHRASE"); passphrase != "" {
+		return newEncryptedWallet(dir, passphrase)
+	}
+	return newDirWallet(dir)
+}
+
+// fileWallet keeps one file per identity in a directory: "<label>.id" holding the identity JSON,
+// or "<label>.enc" holding it encrypted with AES-256-GCM under a key derived from a passphrase
+type fileWallet struct {
+	dir        string
+	extension  string
+	passphrase []byte
+}
+
+// newDirWallet opens a wallet of plain identity files, readable by the Fabric SDKs
+func newDirWallet(dir string) (*fileWallet, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create wallet directory: %w", err)
+	}
+	return &fileWallet{dir: dir, extension: ".id"}, nil
+}
+
+// newEncryptedWallet opens a wallet whose identities are encrypted with a passphrase
+func newEncryptedWallet(dir string, passphrase string) (*fileWallet, error) {
+	if passphrase == "" {
+		return nil, errors.New("an encrypted wallet needs a passphrase")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create wallet directory: %w", err)
+	}
+	return &fileWallet{dir: dir, extension: ".enc", passphrase: []byte(passphrase)}, nil
+}
+
+// Put stores an identity under a label, replacing any identity already stored under it
+func (w *fileWallet) Put(label string, id *walletIdentity) error {
+	file, err := w.file(label)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(id)
+	if err != nil {
+		return fmt.Errorf("failed to marshal identity %s: %w", label, err)
+	}
+	if w.passphrase != nil {
+		data, err = w.seal(label, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Write to a temporary file first so that a failed write leaves the old identity intact
+	temp, err := os.CreateTemp(w.dir, ".put-*")
+	if err != nil {
+		return fmt.Errorf("failed to write identity %s: %w", label, err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write identity %s: %w", label, err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write identity %s: %w", label, err)
+	}
+	if err := os.Rename(temp.Name(), file); err != nil {
+		return fmt.Errorf("failed to write identity %s: %w", label, err)
+	}
+	return nil
+}
+
+// Get reads the identity stored under a label
+func (w *fileWallet) Get(label string) (*walletIdentity, error) {
+	file, err := w.file(label)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrIdentityNotFound, label)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity %s: %w", label, err)
+	}
+	if w.passphrase != nil {
+		data, err = w.open(label, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var walletId walletIdentity
+	if err := json.Unmarshal(data, &walletId); err != nil {
+		return nil, fmt.Errorf("failed to parse identity %s: %w", label, err)
+	}
+	return &walletId, nil
+}
+
+// Fingerprint returns the SHA-256 of the stored identity of a label, which changes whenever the
+// identity is replaced. Unlike Get it needs no decryption, so it is cheap to check on every use.
+func (w *fileWallet) Fingerprint(label string) (string, error) {
+	file, err := w.file(label)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrIdentityNotFound, label)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read identity %s: %w", label, err)
+	}
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// List returns the labels of the wallet in order
+func (w *fileWallet) List() ([]string, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wallet directory: %w", err)
+	}
+
+	labels := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), w.extension) {
+			labels = append(labels, strings.TrimSuffix(entry.Name(), w.extension))
+		}
+	}
+	sort.Strings(labels)
+	return labels, nil
+}
+
+// Remove deletes the identity stored under a label
+func (w *fileWallet) Remove(label string) error {
+	file, err := w.file(label)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrIdentityNotFound, label)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove identity %s: %w", label, err)
+	}
+	return nil
+}
+
+// file returns the path of a label, refusing labels that would leave the wallet directory
+func (w *fileWallet) file(label string) (string, error) {
+	if label == "" || label != filepath.Base(label) || strings.HasPrefix(label, ".") {
+		return "", fmt.Errorf("invalid wallet label %q", label)
+	}
+	return filepath.Join(w.dir, label+w.extension), nil
+}
+
+// sealedIdentity is the file format of an encrypted identity
+type sealedIdentity struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// seal encrypts an identity under a key derived from the passphrase and a fresh salt. The label is
+// authenticated with the identity, so a file renamed to another label does not decrypt.
+func (w *fileWallet) seal(label string, plaintext []byte) ([]byte, error) {
+	sealed := sealedIdentity{Version: 1, KDF: "scrypt", N: scryptN, R: scryptR, P: scryptP, Salt: make([]byte, 16)}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := w.cipher(sealed)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, []byte(label))
+	return json.Marshal(sealed)
+}
+
+// open decrypts an identity written by seal
+func (w *fileWallet) open(label string, data []byte) ([]byte, error) {
+	var sealed sealedIdentity
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted identity %s: %w", label, err)
+	}
+	if sealed.Version != 1 || sealed.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported encryption of identity %s", label)
+	}
+	aead, err := w.cipher(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce in identity %s", label)
+	}
+
+	plaintext, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(label))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt identity %s: wrong passphrase or corrupted file", label)
+	}
+	return plaintext, nil
+}
+
+// cipher derives the AES-256-GCM cipher of an encrypted identity from the passphrase
+func (w *fileWallet) cipher(sealed sealedIdentity) (cipher.AEAD, error) {
+	key, err := scrypt.Key(w.passphrase, sealed.Salt, sealed.N, sealed.R, sealed.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive wallet key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// importOrganizations stores the users of a Fabric "organizations" crypto directory in the
+// wallet, labelled "<user>@<domain>" as in the directory. mspIDs maps the organization domains to
+// their MSP IDs. The labels imported are returned in order.
+func importOrganizations(w wallet, root string, mspIDs map[string]string) ([]string, error) {
+	userDirs, err := filepath.Glob(filepath.Join(root, "peerOrganizations", "*", "users", "*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(userDirs) == 0 {
+		return nil, fmt.Errorf("no users found under %s", filepath.Join(root, "peerOrganizations"))
+	}
+	sort.Strings(userDirs)
+
+	var labels []string
+	for _, userDir := range userDirs {
+		domain := filepath.Base(filepath.Dir(filepath.Dir(userDir)))
+		mspID, ok := mspIDs[domain]
+		if !ok {
+			return labels, fmt.Errorf("no MSP ID known for organization %s", domain)
+		}
+
+		label := filepath.Base(userDir)
+		walletId, err := readMSPIdentity(filepath.Join(userDir, "msp"), mspID)
+		if err != nil {
+			return labels, fmt.Errorf("user %s: %w", label, err)
+		}
+		if err := w.Put(label, walletId); err != nil {
+			return labels, err
+		}
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
+
+// readMSPIdentity reads the enrollment certificate of a local MSP directory and the keystore key
+// that belongs to it
+func readMSPIdentity(mspDir string, mspID string) (*walletIdentity, error) {
+	certFiles, err := filepath.Glob(filepath.Join(mspDir, "signcerts", "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(certFiles) != 1 {
+		return nil, fmt.Errorf("expected one certificate in %s, found %d", filepath.Join(mspDir, "signcerts"), len(certFiles))
+	}
+	certificatePEM, err := os.ReadFile(certFiles[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	return readMSPIdentityFiles(mspID, certificatePEM, filepath.Join(mspDir, "keystore"))
+}
+
+// readMSPIdentityFiles builds an identity from a certificate and the keystore directory, or key
+// file, holding its private key
+func readMSPIdentityFiles(mspID string, certificatePEM []byte, keyPath string) (*walletIdentity, error) {
+	info, err := os.Stat(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	if !info.IsDir() {
+		privateKeyPEM, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key file: %w", err)
+		}
+		return newWalletIdentity(mspID, certificatePEM, privateKeyPEM)
+	}
+
+	certificate, err := identity.CertificateFromPEM(certificatePEM)
+	if err != nil {
+		return nil, err
+	}
+	_, privateKeyPEM, err := findPrivateKey(keyPath, certificate)
+	if err != nil {
+		return nil, err
+	}
+	return newWalletIdentity(mspID, certificatePEM, privateKeyPEM)
+}
+
+// orgMSPIDs maps the organization domains of the profiles to their MSP IDs, e.g.
+// "company.cred.com" to "CompanyMSP"
+func orgMSPIDs(profiles map[string]Config) map[string]string {
+	mspIDs := make(map[string]string)
+	for _, config := range profiles {
+		if config.MSPDir != "" {
+			mspIDs[filepath.Base(filepath.Dir(config.MSPDir))] = config.MSPID
+		}
+	}
+	return mspIDs
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Crypto material imported by "wallet import" when no directory is given
+const defaultOrganizationsDir = "../Network/organizations"
+
+const walletUsage = `usage:
+  wallet list
+  wallet import [organizations-dir]
+  wallet put <label> <mspId> <certPath> <keyPath>
+  wallet remove <label>`
+
+// runWalletCommand manages the wallet from the command line, e.g. "go run . wallet import".
+// Set FABRIC_WALLET_PASSPHRASE to work on an encrypted wallet.
+func runWalletCommand(identities wallet, args []string) error {
+	if len(args) == 0 {
+		return errors.New(walletUsage)
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		labels, err := identities.List()
+		if err != nil {
+			return err
+		}
+		for _, label := range labels {
+			walletId, err := identities.Get(label)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s\t%s\n", label, walletId.MSPID)
+		}
+
+	case args[0] == "import" && len(args) <= 2:
+		dir := defaultOrganizationsDir
+		if len(args) == 2 {
+			dir = args[1]
+		}
+		// The MSP IDs of the organizations come from the connection profiles
+		profiles, err := loadProfiles(profilesPath())
+		if err != nil {
+			return fmt.Errorf("invalid connection profiles: %w", err)
+		}
+		labels, err := importOrganizations(identities, dir, orgMSPIDs(profiles))
+		for _, label := range labels {
+			fmt.Printf("Imported %s\n", label)
+		}
+		if err != nil {
+			return err
+		}
+
+	case args[0] == "put" && len(args) == 5:
+		certificatePEM, err := os.ReadFile(args[3])
+		if err != nil {
+			return fmt.Errorf("failed to read certificate file: %w", err)
+		}
+		walletId, err := readMSPIdentityFiles(args[2], certificatePEM, args[4])
+		if err != nil {
+			return err
+		}
+		if err := identities.Put(args[1], walletId); err != nil {
+			return err
+		}
+		fmt.Printf("Stored %s\n", args[1])
+
+	case args[0] == "remove" && len(args) == 2:
+		if err := identities.Remove(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", args[1])
+
+	default:
+		return errors.New(walletUsage)
+	}
+	return nil
+}
@@ -46,12 +46,12 @@ function createUniversity() {
 
   echo "Registering user"
   set -x
-  fabric-ca-client register --caname ca-university --id.name user1 --id.secret user1pw --id.type client --tls.certfiles "${PWD}/organizations/fabric-ca/university/ca-cert.pem"
+  fabric-ca-client register --caname ca-university --id.name user1 --id.secret user1pw --id.type client --id.attrs 'role=registrar:ecert' --tls.certfiles "${PWD}/organizations/fabric-ca/university/ca-cert.pem"
   { set +x; } 2>/dev/null
 
   echo "Registering the org admin"
   set -x
-  fabric-ca-client register --caname ca-university --id.name universityadmin --id.secret universityadminpw --id.type admin --tls.certfiles "${PWD}/organizations/fabric-ca/university/ca-cert.pem"
+  fabric-ca-client register --caname ca-university --id.name universityadmin --id.secret universityadminpw --id.type admin --id.attrs 'role=governor:ecert' --tls.certfiles "${PWD}/organizations/fabric-ca/university/ca-cert.pem"
   { set +x; } 2>/dev/null
 
   echo "Generating the peer0 msp"
@@ -132,7 +132,7 @@ function createStudent() {
 
   echo "Registering user"
   set -x
-  fabric-ca-client register --caname ca-student --id.name user1 --id.secret user1pw --id.type client --tls.certfiles "${PWD}/organizations/fabric-ca/student/ca-cert.pem"
+  fabric-ca-client register --caname ca-student --id.name user1 --id.secret user1pw --id.type client --id.attrs 'role=student:ecert,studentId=Stu1:ecert' --tls.certfiles "${PWD}/organizations/fabric-ca/student/ca-cert.pem"
   { set +x; } 2>/dev/null
 
   echo "Registering the org admin"
@@ -219,7 +219,7 @@ function createCompany() {
 
   echo "Registering user"
   set -x
-  fabric-ca-client register --caname ca-company --id.name user1 --id.secret user1pw --id.type client --tls.certfiles "${PWD}/organizations/fabric-ca/company/ca-cert.pem"
+  fabric-ca-client register --caname ca-company --id.name user1 --id.secret user1pw --id.type client --id.attrs 'role=hr:ecert,companyId=NPCI:ecert' --tls.certfiles "${PWD}/organizations/fabric-ca/company/ca-cert.pem"
   { set +x; } 2>/dev/null
 
   echo "Registering the org admin"
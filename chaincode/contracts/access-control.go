@@ -0,0 +1,181 @@
+package contracts
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Roles carried in the "role" attribute of Fabric CA enrollment certificates
+const (
+	RoleRegistrar string = "registrar" // University staff who issue and correct results
+	RoleAuditor   string = "auditor"   // University staff with read-only access
+	RoleStudent   string = "student"   // Students, identified further by the studentId attribute
+	RoleHR        string = "hr"        // Company recruiters
+	RoleGovernor  string = "governor"  // Consortium members who maintain the institution registry
+)
+
+// Actions guarded by the policy table
+const (
+	ActionResultCreate      string = "result.create"
+	ActionResultAmend       string = "result.amend"
+	ActionResultRevoke      string = "result.revoke"
+	ActionResultDelete      string = "result.delete"
+	ActionResultConfigure   string = "result.configure"
+	ActionResultRead        string = "result.read"
+	ActionTranscriptWrite   string = "transcript.write"
+	ActionTranscriptRead    string = "transcript.read"
+	ActionDocumentAnchor    string = "document.anchor"
+	ActionDocumentVerify    string = "document.verify"
+	ActionDisclosureCommit  string = "disclosure.commit"
+	ActionDisclosureVerify  string = "disclosure.verify"
+	ActionOfferCreate       string = "offer.create"
+	ActionOfferRead         string = "offer.read"
+	ActionOfferDelete       string = "offer.delete"
+	ActionOfferVerify       string = "offer.verify"
+	ActionOfferRespond      string = "offer.respond"
+	ActionOfferManage       string = "offer.manage"
+	ActionOfferCommitment   string = "offer.commitment"
+	ActionOfferNegotiate    string = "offer.negotiate"
+	ActionOfferMigrate      string = "offer.migrate"
+	ActionPolicyManage      string = "eligibility.manage"
+	ActionPolicyEvaluate    string = "eligibility.evaluate"
+	ActionVerificationOpen  string = "verification.open"
+	ActionVerificationSign  string = "verification.sign"
+	ActionVerificationRead  string = "verification.read"
+	ActionConsentManage     string = "consent.manage"
+	ActionConsentRead       string = "consent.read"
+	ActionInstitutionGovern string = "institution.govern"
+	ActionInstitutionRead   string = "institution.read"
+)
+
+// policyRule allows identities of an MSP holding a role. Both must match, since any
+// organization's CA could issue a certificate carrying any role attribute. When
+// Institution is set, the MSP must instead be an active entry of the institution registry.
+type policyRule struct {
+	MSP         string
+	Role        string
+	Institution bool
+}
+
+var (
+	institutionRegistrar = policyRule{Institution: true, Role: RoleRegistrar}
+	institutionAuditor   = policyRule{Institution: true, Role: RoleAuditor}
+	studentMember        = policyRule{MSP: "StudentMSP", Role: RoleStudent}
+	companyHR            = policyRule{MSP: "CompanyMSP", Role: RoleHR}
+	consortiumGovernors  = []policyRule{
+		{MSP: "UniversityMSP", Role: RoleGovernor},
+		{MSP: "StudentMSP", Role: RoleGovernor},
+		{MSP: "CompanyMSP", Role: RoleGovernor},
+	}
+)
+
+// policyTable lists, per action, the MSP and role combinations that may perform it
+var policyTable = map[string][]policyRule{
+	ActionResultCreate:      {institutionRegistrar},
+	ActionResultAmend:       {institutionRegistrar},
+	ActionResultRevoke:      {institutionRegistrar},
+	ActionResultDelete:      {institutionRegistrar},
+	ActionResultConfigure:   {institutionRegistrar},
+	ActionResultRead:        {institutionRegistrar, institutionAuditor, studentMember, companyHR},
+	ActionTranscriptWrite:   {institutionRegistrar},
+	ActionTranscriptRead:    {institutionRegistrar, institutionAuditor, studentMember, companyHR},
+	ActionDocumentAnchor:    {institutionRegistrar},
+	ActionDocumentVerify:    {institutionRegistrar, institutionAuditor, studentMember, companyHR},
+	ActionDisclosureCommit:  {institutionRegistrar},
+	ActionDisclosureVerify:  {institutionRegistrar, institutionAuditor, studentMember, companyHR},
+	ActionOfferCreate:       {companyHR},
+	ActionOfferRead:         {companyHR, studentMember},
+	ActionOfferDelete:       {companyHR},
+	ActionOfferVerify:       {companyHR},
+	ActionOfferRespond:      {studentMember},
+	ActionOfferManage:       {companyHR},
+	ActionOfferCommitment:   {institutionRegistrar, institutionAuditor, studentMember, companyHR},
+	ActionOfferNegotiate:    {companyHR, studentMember},
+	ActionOfferMigrate:      consortiumGovernors,
+	ActionPolicyManage:      {companyHR},
+	ActionPolicyEvaluate:    {companyHR},
+	ActionVerificationOpen:  {companyHR},
+	ActionVerificationSign:  {institutionRegistrar},
+	ActionVerificationRead:  {institutionRegistrar, institutionAuditor, studentMember, companyHR},
+	ActionConsentManage:     {studentMember},
+	ActionConsentRead:       {institutionRegistrar, institutionAuditor, studentMember},
+	ActionInstitutionGovern: consortiumGovernors,
+	ActionInstitutionRead:   append([]policyRule{institutionRegistrar, institutionAuditor, studentMember, companyHR}, consortiumGovernors...),
+}
+
+// authorize checks the caller's MSP and role attribute against the policy table
+// and returns the role under which the action is allowed
+func authorize(ctx contractapi.TransactionContextInterface, action string) (string, error) {
+	rules, ok := policyTable[action]
+	if !ok {
+		return "", fmt.Errorf("no access policy defined for action %s", action)
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+
+	role, _, err := ctx.GetClientIdentity().GetAttributeValue("role")
+	if err != nil {
+		return "", fmt.Errorf("could not read role attribute: %v", err)
+	}
+
+	for _, rule := range rules {
+		if rule.Role != role {
+			continue
+		}
+		if !rule.Institution && rule.MSP == clientOrgID {
+			return role, nil
+		}
+		if rule.Institution {
+			active, err := isActiveInstitution(ctx, clientOrgID)
+			if err != nil {
+				return "", err
+			}
+			if active {
+				return role, nil
+			}
+		}
+	}
+
+	return "", newChaincodeError(CodeForbidden, "identity under MSPID %v with role %q is not allowed to perform %s", clientOrgID, role, action)
+}
+
+// isOfferIssuer reports whether recruiters of an MSP may issue offers
+func isOfferIssuer(mspId string) bool {
+	for _, rule := range policyTable[ActionOfferCreate] {
+		if rule.MSP == mspId {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeIssuer restricts changes to a result to registrars of the institution that issued it
+func authorizeIssuer(ctx contractapi.TransactionContextInterface, result *Result) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	if result.IssuerMSP != clientOrgID {
+		return newChaincodeError(CodeForbidden, "result %s was issued by %q and cannot be changed by %v", result.ResultId, result.IssuerMSP, clientOrgID)
+	}
+	return nil
+}
+
+// clientStudentId identifies the calling student from the studentId certificate attribute,
+// falling back to the Fabric CA enrollment ID
+func clientStudentId(ctx contractapi.TransactionContextInterface) (string, error) {
+	for _, attribute := range []string{"studentId", "hf.EnrollmentID"} {
+		value, found, err := ctx.GetClientIdentity().GetAttributeValue(attribute)
+		if err != nil {
+			return "", fmt.Errorf("could not read %s attribute: %v", attribute, err)
+		}
+		if found && value != "" {
+			return value, nil
+		}
+	}
+	return "", newChaincodeError(CodeForbidden, "client certificate does not carry a studentId attribute")
+}
@@ -0,0 +1,295 @@
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// registerInstitution stores a registry entry the way AddInstitution does
+func registerInstitution(t *testing.T, stub *mockStub, mspId string, status string, validFrom string, validTo string) {
+	t.Helper()
+	key, _ := stub.CreateCompositeKey(institutionObjectType, []string{mspId})
+	institutionBytes, err := json.Marshal(Institution{
+		AssetType: "Institution",
+		MspId:     mspId,
+		Name:      mspId,
+		Status:    status,
+		ValidFrom: validFrom,
+		ValidTo:   validTo,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub.state[key] = institutionBytes
+}
+
+func newRegistryStub(t *testing.T) *mockStub {
+	stub := newMockStub()
+	registerInstitution(t, stub, "UniversityMSP", InstitutionActive, "2025-01-01T00:00:00Z", "2027-01-01T00:00:00Z")
+	registerInstitution(t, stub, "SuspendedMSP", InstitutionSuspended, "2025-01-01T00:00:00Z", "2027-01-01T00:00:00Z")
+	registerInstitution(t, stub, "ExpiredMSP", InstitutionActive, "2020-01-01T00:00:00Z", "2025-01-01T00:00:00Z")
+	return stub
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		action  string
+		msp     string
+		role    string
+		allowed bool
+	}{
+		// Result writes belong to registrars of active institutions
+		{ActionResultCreate, "UniversityMSP", RoleRegistrar, true},
+		{ActionResultCreate, "UniversityMSP", RoleAuditor, false},
+		{ActionResultCreate, "UniversityMSP", "", false},
+		{ActionResultCreate, "SuspendedMSP", RoleRegistrar, false},
+		{ActionResultCreate, "ExpiredMSP", RoleRegistrar, false},
+		{ActionResultCreate, "UnknownMSP", RoleRegistrar, false},
+		{ActionResultCreate, "StudentMSP", RoleRegistrar, false},
+		{ActionResultCreate, "CompanyMSP", RoleRegistrar, false},
+		{ActionResultAmend, "UniversityMSP", RoleRegistrar, true},
+		{ActionResultAmend, "CompanyMSP", RoleHR, false},
+		{ActionResultRevoke, "UniversityMSP", RoleRegistrar, true},
+		{ActionResultRevoke, "UniversityMSP", RoleAuditor, false},
+		{ActionResultRevoke, "StudentMSP", RoleStudent, false},
+		{ActionResultDelete, "UniversityMSP", RoleRegistrar, true},
+		{ActionResultDelete, "UniversityMSP", RoleGovernor, false},
+
+		// Result reads
+		{ActionResultRead, "UniversityMSP", RoleRegistrar, true},
+		{ActionResultRead, "UniversityMSP", RoleAuditor, true},
+		{ActionResultRead, "SuspendedMSP", RoleAuditor, false},
+		{ActionResultRead, "StudentMSP", RoleStudent, true},
+		{ActionResultRead, "CompanyMSP", RoleHR, true},
+		{ActionResultRead, "CompanyMSP", RoleStudent, false},
+		{ActionResultRead, "StudentMSP", RoleHR, false},
+		{ActionResultRead, "CompanyMSP", "", false},
+
+		// Transcripts
+		{ActionTranscriptWrite, "UniversityMSP", RoleRegistrar, true},
+		{ActionTranscriptWrite, "UniversityMSP", RoleAuditor, false},
+		{ActionTranscriptRead, "StudentMSP", RoleStudent, true},
+		{ActionTranscriptRead, "CompanyMSP", RoleHR, true},
+		{ActionTranscriptRead, "CompanyMSP", RoleGovernor, false},
+
+		// Offers
+		{ActionOfferCreate, "CompanyMSP", RoleHR, true},
+		{ActionOfferCreate, "StudentMSP", RoleStudent, false},
+		{ActionOfferCreate, "UniversityMSP", RoleRegistrar, false},
+		{ActionOfferCreate, "UniversityMSP", RoleHR, false},
+		{ActionOfferRead, "CompanyMSP", RoleHR, true},
+		{ActionOfferRead, "StudentMSP", RoleStudent, true},
+		{ActionOfferRead, "UniversityMSP", RoleAuditor, false},
+		{ActionOfferRespond, "StudentMSP", RoleStudent, true},
+		{ActionOfferRespond, "CompanyMSP", RoleHR, false},
+		{ActionOfferManage, "CompanyMSP", RoleHR, true},
+		{ActionOfferManage, "StudentMSP", RoleStudent, false},
+		{ActionOfferNegotiate, "StudentMSP", RoleStudent, true},
+		{ActionOfferNegotiate, "CompanyMSP", RoleHR, true},
+		{ActionOfferNegotiate, "UniversityMSP", RoleRegistrar, false},
+		{ActionOfferMigrate, "CompanyMSP", RoleGovernor, true},
+		{ActionOfferMigrate, "StudentMSP", RoleGovernor, true},
+		{ActionOfferMigrate, "CompanyMSP", RoleHR, false},
+
+		// Consent
+		{ActionConsentManage, "StudentMSP", RoleStudent, true},
+		{ActionConsentManage, "CompanyMSP", RoleHR, false},
+		{ActionConsentManage, "UniversityMSP", RoleRegistrar, false},
+		{ActionConsentRead, "UniversityMSP", RoleAuditor, true},
+		{ActionConsentRead, "CompanyMSP", RoleHR, false},
+
+		// Verification requests
+		{ActionVerificationOpen, "CompanyMSP", RoleHR, true},
+		{ActionVerificationOpen, "StudentMSP", RoleStudent, false},
+		{ActionVerificationSign, "UniversityMSP", RoleRegistrar, true},
+		{ActionVerificationSign, "UniversityMSP", RoleAuditor, false},
+		{ActionVerificationSign, "CompanyMSP", RoleHR, false},
+
+		// Institution registry
+		{ActionInstitutionGovern, "UniversityMSP", RoleGovernor, true},
+		{ActionInstitutionGovern, "StudentMSP", RoleGovernor, true},
+		{ActionInstitutionGovern, "CompanyMSP", RoleGovernor, true},
+		{ActionInstitutionGovern, "UnknownMSP", RoleGovernor, false},
+		{ActionInstitutionGovern, "UniversityMSP", RoleRegistrar, false},
+		{ActionInstitutionRead, "CompanyMSP", RoleHR, true},
+		{ActionInstitutionRead, "UnknownMSP", RoleHR, false},
+	}
+
+	stub := newRegistryStub(t)
+	for _, test := range tests {
+		t.Run(test.action+"/"+test.msp+"/"+test.role, func(t *testing.T) {
+			role, err := authorize(newMockContext(stub, test.msp, test.role), test.action)
+			if test.allowed {
+				if err != nil {
+					t.Fatalf("expected %s with role %q to be allowed: %v", test.msp, test.role, err)
+				}
+				if role != test.role {
+					t.Fatalf("expected role %q, got %q", test.role, role)
+				}
+			} else if err == nil {
+				t.Fatalf("expected %s with role %q to be denied", test.msp, test.role)
+			}
+		})
+	}
+}
+
+// TestAuthorizeRequiresMSPAndRole checks every action of the policy table: each rule admits its
+// own MSP and role, and neither a foreign MSP with the role nor the MSP without the role
+func TestAuthorizeRequiresMSPAndRole(t *testing.T) {
+	stub := newRegistryStub(t)
+	for action, rules := range policyTable {
+		for _, rule := range rules {
+			msp := rule.MSP
+			if rule.Institution {
+				msp = "UniversityMSP"
+			}
+			if _, err := authorize(newMockContext(stub, msp, rule.Role), action); err != nil {
+				t.Errorf("%s: %s with role %q denied: %v", action, msp, rule.Role, err)
+			}
+			if _, err := authorize(newMockContext(stub, "UnknownMSP", rule.Role), action); err == nil {
+				t.Errorf("%s: unregistered MSP with role %q allowed", action, rule.Role)
+			}
+			if _, err := authorize(newMockContext(stub, msp, ""), action); err == nil {
+				t.Errorf("%s: %s without a role allowed", action, msp)
+			}
+		}
+	}
+}
+
+func TestAuthorizeUnknownAction(t *testing.T) {
+	if _, err := authorize(newMockContext(newRegistryStub(t), "UniversityMSP", RoleRegistrar), "result.unknown"); err == nil {
+		t.Fatal("expected an action without a policy to be denied")
+	}
+}
+
+// newStudentContext returns a transaction context for a student with the given enrollment ID
+func newStudentContext(stub *mockStub, studentId string) contractapi.TransactionContextInterface {
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(&mockIdentity{mspId: "StudentMSP", attributes: map[string]string{"hf.EnrollmentID": studentId, "role": RoleStudent}})
+	return ctx
+}
+
+// requireForbidden fails the test unless err is a FORBIDDEN chaincode error
+func requireForbidden(t *testing.T, err error) {
+	t.Helper()
+	var chaincodeError *ChaincodeError
+	if !errors.As(err, &chaincodeError) || chaincodeError.Code != CodeForbidden {
+		t.Fatalf("expected a %s error, got %v", CodeForbidden, err)
+	}
+}
+
+func TestResultWritesRequireTheIssuingRegistrar(t *testing.T) {
+	stub := newRegistryStub(t)
+	registerInstitution(t, stub, "OtherUniversityMSP", InstitutionActive, "2025-01-01T00:00:00Z", "2027-01-01T00:00:00Z")
+	stub.defineCollections(marksCollectionName("OtherUniversityMSP"), disclosureCollectionName("OtherUniversityMSP"))
+	contract := new(ResultContract)
+
+	_, err := contract.CreateResult(newMockContext(stub, "UniversityMSP", RoleAuditor), "RES1", "user1", 100, 80)
+	requireForbidden(t, err)
+
+	registrar := newMockContext(stub, "UniversityMSP", RoleRegistrar)
+	_, err = contract.CreateResult(registrar, "RES1", "user1", 100, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := getResult(registrar, "RES1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign := newMockContext(stub, "OtherUniversityMSP", RoleRegistrar)
+	requireForbidden(t, authorizeIssuer(foreign, result))
+	_, err = contract.RevokeResult(foreign, "RES1", "Issued in error")
+	requireForbidden(t, err)
+	_, err = contract.DeleteResult(foreign, "RES1")
+	requireForbidden(t, err)
+	if err := authorizeIssuer(registrar, result); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOfferAccessRequiresAParty(t *testing.T) {
+	stub := newRegistryStub(t)
+	recruiter := newMockContext(stub, "CompanyMSP", RoleHR)
+	err := putOffer(recruiter, &Offer{
+		OfferId:    "OFFER1",
+		AssetType:  "OfferLetter",
+		StudentId:  "user1",
+		CompanyMSP: "CompanyMSP",
+		Status:     OfferIssued,
+		IssuedAt:   "2025-06-01T00:00:00Z",
+		ValidUntil: "2026-12-31T00:00:00Z",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The student the offer is addressed to and the issuing company are parties
+	for _, ctx := range []contractapi.TransactionContextInterface{recruiter, newStudentContext(stub, "user1")} {
+		if _, err := readAuthorizedOffer(ctx, ActionOfferRead, "OFFER1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := readNegotiableOffer(ctx, "OFFER1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Other students are not, whatever the action
+	other := newStudentContext(stub, "user2")
+	_, err = readAuthorizedOffer(other, ActionOfferRead, "OFFER1")
+	requireForbidden(t, err)
+	_, _, err = readNegotiableOffer(other, "OFFER1")
+	requireForbidden(t, err)
+	_, err = new(OfferContract).ReadOffer(other, "OFFER1")
+	requireForbidden(t, err)
+	_, err = new(OfferContract).AcceptOffer(other, "OFFER1")
+	requireForbidden(t, err)
+
+	// Companies other than the issuer, and the issuer's other roles, are refused before any read
+	_, err = readAuthorizedOffer(newMockContext(stub, "UniversityMSP", RoleHR), ActionOfferRead, "OFFER1")
+	requireForbidden(t, err)
+	_, err = readAuthorizedOffer(newMockContext(stub, "CompanyMSP", RoleGovernor), ActionOfferManage, "OFFER1")
+	requireForbidden(t, err)
+}
+
+func TestVerificationRequiresTheAddressedParties(t *testing.T) {
+	stub := newRegistryStub(t)
+	registerInstitution(t, stub, "OtherUniversityMSP", InstitutionActive, "2025-01-01T00:00:00Z", "2027-01-01T00:00:00Z")
+	registrar := newMockContext(stub, "UniversityMSP", RoleRegistrar)
+	err := putVerificationRequest(registrar, &VerificationRequest{
+		AssetType:      "VerificationRequest",
+		RequestId:      "VR1",
+		CompanyMSP:     "CompanyMSP",
+		StudentId:      "user1",
+		ResultId:       "RES1",
+		IssuerMSP:      "UniversityMSP",
+		ConsentGrantId: "G1",
+		Status:         VerificationPending,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract := new(VerificationContract)
+
+	// Only the institution the request is addressed to decides it
+	foreign := newMockContext(stub, "OtherUniversityMSP", RoleRegistrar)
+	_, err = readDecidableRequest(foreign, "VR1")
+	requireForbidden(t, err)
+	_, err = decideVerification(foreign, "VR1", VerificationRejected, "No such record", "")
+	requireForbidden(t, err)
+	_, err = contract.RejectVerification(newMockContext(stub, "UniversityMSP", RoleAuditor), "VR1", "No such record", "")
+	requireForbidden(t, err)
+	if _, err := readDecidableRequest(registrar, "VR1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the requesting company may cancel it or read the marks it confirms
+	_, err = contract.CancelVerification(newMockContext(stub, "UniversityMSP", RoleHR), "VR1", "No longer needed")
+	requireForbidden(t, err)
+	_, err = contract.GetVerifiedMarks(newMockContext(stub, "StudentMSP", RoleStudent), "VR1")
+	requireForbidden(t, err)
+}
@@ -0,0 +1,46 @@
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Codes of ChaincodeError. Clients map them to their responses instead of parsing messages;
+// OfferPurgedError and OfferValidationError carry the codes OFFER_PURGED, INVALID_OFFER and
+// INVALID_REVISION. Errors without a code are internal failures.
+const (
+	CodeNotFound        string = "NOT_FOUND"        // The asset does not exist
+	CodeForbidden       string = "FORBIDDEN"        // The caller may not act on the asset
+	CodeConflict        string = "CONFLICT"         // The state of the asset does not allow the change
+	CodeInvalidArgument string = "INVALID_ARGUMENT" // An argument or transient field was rejected
+)
+
+// ChaincodeError is a failure a client can act on. Like OfferValidationError, its message is JSON.
+type ChaincodeError struct {
+	Code    string `json:"code"`    // One of the Code constants
+	Message string `json:"message"` // Human readable explanation
+}
+
+func (e *ChaincodeError) Error() string {
+	errorBytes, err := json.Marshal(e)
+	if err != nil {
+		return e.Message
+	}
+	return string(errorBytes)
+}
+
+// newChaincodeError builds a coded error with a formatted message
+func newChaincodeError(code string, format string, args ...interface{}) error {
+	return &ChaincodeError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// errorMessage returns the explanation of an error, without the code of a ChaincodeError, for
+// errors wrapped into another message
+func errorMessage(err error) string {
+	var coded *ChaincodeError
+	if errors.As(err, &coded) {
+		return coded.Message
+	}
+	return err.Error()
+}
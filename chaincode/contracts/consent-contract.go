@@ -0,0 +1,584 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ConsentContract lets students control which companies may read their results
+type ConsentContract struct {
+	contractapi.Contract
+}
+
+// Object types of the composite keys used by the consent subsystem
+const (
+	consentObjectType      string = "consent"      // (grantId)
+	consentEventObjectType string = "consentEvent" // (studentId, txId, subject)
+)
+
+// Consent audit actions
+const (
+	ConsentGranted  string = "GRANTED"
+	ConsentRevoked  string = "REVOKED"
+	ConsentAccessed string = "ACCESSED"
+)
+
+// Reserved entry of a grant's resultIds that extends it to the student's course grades and transcript
+const TranscriptConsent string = "transcript"
+
+// ConsentGrant gives a company identity time-limited read access to specific results of a student
+type ConsentGrant struct {
+	AssetType  string   `json:"assetType"`                                // Asset type ("ConsentGrant")
+	GrantId    string   `json:"grantId"`                                  // Unique identifier for the grant
+	StudentId  string   `json:"studentId"`                                // Student who owns the results
+	GranteeMSP string   `json:"granteeMsp"`                               // MSP of the company allowed to read
+	Grantee    string   `json:"grantee"`                                  // Company client identity or company ID allowed to read
+	ResultIds  []string `json:"resultIds"`                                // Results covered by the grant, and TranscriptConsent for course grades
+	ValidFrom  string   `json:"validFrom"`                                // Start of the grant (RFC3339)
+	ValidUntil string   `json:"validUntil"`                               // End of the grant (RFC3339)
+	GrantedBy  string   `json:"grantedBy"`                                // Student client identity that issued the grant
+	Revoked    bool     `json:"revoked"`                                  // Whether the student has withdrawn the grant
+	RevokedAt  string   `json:"revokedAt,omitempty" metadata:",optional"` // When the grant was withdrawn (RFC3339)
+}
+
+// ConsentEvent is an audit entry of a grant, revocation or company read of a student's result
+type ConsentEvent struct {
+	AssetType string `json:"assetType"`                               // Asset type ("ConsentEvent")
+	StudentId string `json:"studentId"`                               // Student whose data is concerned
+	Action    string `json:"action"`                                  // GRANTED, REVOKED or ACCESSED
+	GrantId   string `json:"grantId"`                                 // Grant that was issued, revoked or used
+	ResultId  string `json:"resultId,omitempty" metadata:",optional"` // Result read, or TranscriptConsent, for ACCESSED entries
+	Actor     string `json:"actor"`                                   // Client identity that acted
+	ActorMSP  string `json:"actorMsp"`                                // MSP of the acting identity
+	Timestamp string `json:"timestamp"`                               // Transaction timestamp (RFC3339)
+	TxId      string `json:"txId"`                                    // Transaction ID
+}
+
+// GrantConsent lets a student give a company read access to some of their results until validUntil (RFC3339).
+// Listing TranscriptConsent among the resultIds also gives access to the student's course grades.
+func (c *ConsentContract) GrantConsent(ctx contractapi.TransactionContextInterface, grantId string, granteeMSP string, grantee string, resultIds []string, validUntil string) (string, error) {
+	if strings.TrimSpace(grantId) == "" || strings.TrimSpace(granteeMSP) == "" || strings.TrimSpace(grantee) == "" {
+		return "", newChaincodeError(CodeInvalidArgument, "grantId, granteeMSP and grantee cannot be empty")
+	}
+	if len(resultIds) == 0 {
+		return "", newChaincodeError(CodeInvalidArgument, "at least one resultId must be granted")
+	}
+
+	_, err := authorize(ctx, ActionConsentManage)
+	if err != nil {
+		return "", err
+	}
+
+	studentId, err := clientStudentId(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// Every granted result must belong to the calling student
+	for _, resultId := range resultIds {
+		if resultId == TranscriptConsent {
+			continue
+		}
+		result, err := getResult(ctx, resultId)
+		if err != nil {
+			return "", err
+		}
+		if result.StudentId != studentId {
+			return "", newChaincodeError(CodeForbidden, "result %s does not belong to student %s", resultId, studentId)
+		}
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	until, err := time.Parse(time.RFC3339, validUntil)
+	if err != nil {
+		return "", newChaincodeError(CodeInvalidArgument, "validUntil must be an RFC3339 timestamp: %v", err)
+	}
+	if !until.After(now) {
+		return "", newChaincodeError(CodeInvalidArgument, "validUntil %s must be in the future", validUntil)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(consentObjectType, []string{grantId})
+	if err != nil {
+		return "", fmt.Errorf("could not create consent key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return "", newChaincodeError(CodeConflict, "consent grant %s already exists", grantId)
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+
+	grant := ConsentGrant{
+		AssetType:  "ConsentGrant",
+		GrantId:    grantId,
+		StudentId:  studentId,
+		GranteeMSP: granteeMSP,
+		Grantee:    grantee,
+		ResultIds:  resultIds,
+		ValidFrom:  now.Format(time.RFC3339),
+		ValidUntil: until.UTC().Format(time.RFC3339),
+		GrantedBy:  clientID,
+	}
+
+	err = putConsentGrant(ctx, key, &grant)
+	if err != nil {
+		return "", err
+	}
+
+	err = recordConsentEvent(ctx, studentId, ConsentGranted, grantId, "")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Consent %v granted to %v until %v", grantId, grantee, grant.ValidUntil), nil
+}
+
+// RevokeConsent withdraws a grant before it expires
+func (c *ConsentContract) RevokeConsent(ctx contractapi.TransactionContextInterface, grantId string) (string, error) {
+	_, err := authorize(ctx, ActionConsentManage)
+	if err != nil {
+		return "", err
+	}
+
+	studentId, err := clientStudentId(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	grant, err := c.ReadConsent(ctx, grantId)
+	if err != nil {
+		return "", err
+	}
+	if grant.StudentId != studentId {
+		return "", newChaincodeError(CodeForbidden, "consent grant %s does not belong to student %s", grantId, studentId)
+	}
+	if grant.Revoked {
+		return "", newChaincodeError(CodeConflict, "consent grant %s is already revoked", grantId)
+	}
+
+	revokedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	grant.Revoked = true
+	grant.RevokedAt = revokedAt
+
+	key, err := ctx.GetStub().CreateCompositeKey(consentObjectType, []string{grantId})
+	if err != nil {
+		return "", fmt.Errorf("could not create consent key: %v", err)
+	}
+	err = putConsentGrant(ctx, key, grant)
+	if err != nil {
+		return "", err
+	}
+
+	err = recordConsentEvent(ctx, studentId, ConsentRevoked, grantId, "")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Consent %v revoked", grantId), nil
+}
+
+// ReadConsent retrieves a consent grant from the world state
+func (c *ConsentContract) ReadConsent(ctx contractapi.TransactionContextInterface, grantId string) (*ConsentGrant, error) {
+	grant, err := getConsentGrant(ctx, grantId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorizeStudentData(ctx, grant.StudentId)
+	if err != nil {
+		return nil, err
+	}
+
+	return grant, nil
+}
+
+// getConsentGrant reads a consent grant without applying authorization
+func getConsentGrant(ctx contractapi.TransactionContextInterface, grantId string) (*ConsentGrant, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(consentObjectType, []string{grantId})
+	if err != nil {
+		return nil, fmt.Errorf("could not create consent key: %v", err)
+	}
+
+	grantBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if grantBytes == nil {
+		return nil, newChaincodeError(CodeNotFound, "the consent grant %s does not exist", grantId)
+	}
+
+	var grant ConsentGrant
+	err = json.Unmarshal(grantBytes, &grant)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal consent grant: %v", err)
+	}
+
+	return &grant, nil
+}
+
+// GetConsentsByStudent lists every grant a student has issued
+func (c *ConsentContract) GetConsentsByStudent(ctx contractapi.TransactionContextInterface, studentId string) ([]*ConsentGrant, error) {
+	err := authorizeStudentData(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	queryString := fmt.Sprintf(`{"selector":{"assetType":"ConsentGrant","studentId":%q}}`, studentId)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch consent grants: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var grants []*ConsentGrant
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch consent grant: %v", err)
+		}
+
+		var grant ConsentGrant
+		err = json.Unmarshal(queryResult.Value, &grant)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal consent grant: %v", err)
+		}
+		grants = append(grants, &grant)
+	}
+
+	return grants, nil
+}
+
+// GetConsentAuditTrail lists grants, revocations and company reads of a student's data
+func (c *ConsentContract) GetConsentAuditTrail(ctx contractapi.TransactionContextInterface, studentId string) ([]*ConsentEvent, error) {
+	err := authorizeStudentData(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	eventsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(consentEventObjectType, []string{studentId})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch consent events: %v", err)
+	}
+	defer eventsIterator.Close()
+
+	var events []*ConsentEvent
+	for eventsIterator.HasNext() {
+		queryResult, err := eventsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch consent event: %v", err)
+		}
+
+		var event ConsentEvent
+		err = json.Unmarshal(queryResult.Value, &event)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal consent event: %v", err)
+		}
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// authorizeResultRead enforces who may read a result: registrars and auditors always, a student only
+// their own results, and a company recruiter only through an active consent grant, which is logged.
+func authorizeResultRead(ctx contractapi.TransactionContextInterface, result *Result) error {
+	role, err := authorize(ctx, ActionResultRead)
+	if err != nil {
+		return err
+	}
+
+	switch role {
+	case RoleStudent:
+		studentId, err := clientStudentId(ctx)
+		if err != nil {
+			return err
+		}
+		if studentId != result.StudentId {
+			return newChaincodeError(CodeForbidden, "student %s cannot read result %s", studentId, result.ResultId)
+		}
+		return nil
+	case RoleHR:
+		clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+		if err != nil {
+			return fmt.Errorf("could not fetch client identity: %s", err)
+		}
+		grant, err := findActiveGrant(ctx, clientOrgID, result.StudentId, result.ResultId)
+		if err != nil {
+			return err
+		}
+		if grant == nil {
+			return newChaincodeError(CodeForbidden, "no active consent allows this identity to read result %s", result.ResultId)
+		}
+		return recordConsentEvent(ctx, result.StudentId, ConsentAccessed, grant.GrantId, result.ResultId)
+	default:
+		return nil
+	}
+}
+
+// filterReadableResults keeps only the results the caller may read
+func filterReadableResults(ctx contractapi.TransactionContextInterface, results []*Result) []*Result {
+	var readable []*Result
+	for _, result := range results {
+		if authorizeResultRead(ctx, result) == nil {
+			readable = append(readable, result)
+		}
+	}
+	return readable
+}
+
+// readableResultsWithMarks keeps the results the caller may read and loads their private marks
+func readableResultsWithMarks(ctx contractapi.TransactionContextInterface, results []*Result) ([]*Result, error) {
+	readable := filterReadableResults(ctx, results)
+	err := loadResultMarks(ctx, readable...)
+	if err != nil {
+		return nil, err
+	}
+	return readable, nil
+}
+
+// findActiveGrant returns the first unexpired, unrevoked grant of a student covering resultId for the
+// calling company identity
+func findActiveGrant(ctx contractapi.TransactionContextInterface, clientOrgID string, studentId string, resultId string) (*ConsentGrant, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	companyId, _, err := ctx.GetClientIdentity().GetAttributeValue("companyId")
+	if err != nil {
+		return nil, fmt.Errorf("could not read companyId attribute: %v", err)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queryString := fmt.Sprintf(`{"selector":{"assetType":"ConsentGrant","studentId":%q,"granteeMsp":%q,"revoked":false,"resultIds":{"$elemMatch":{"$eq":%q}}}}`, studentId, clientOrgID, resultId)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch consent grants: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch consent grant: %v", err)
+		}
+
+		var grant ConsentGrant
+		err = json.Unmarshal(queryResult.Value, &grant)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal consent grant: %v", err)
+		}
+
+		if grant.names(clientID, companyId) && grant.isActive(now) {
+			return &grant, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// getConsentedResults reads the results covered by the active grants of the calling company
+// identity, across every student who granted them. Results deleted since are skipped.
+func getConsentedResults(ctx contractapi.TransactionContextInterface) ([]*Result, error) {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	companyId, _, err := ctx.GetClientIdentity().GetAttributeValue("companyId")
+	if err != nil {
+		return nil, fmt.Errorf("could not read companyId attribute: %v", err)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queryString := fmt.Sprintf(`{"selector":{"assetType":"ConsentGrant","granteeMsp":%q,"revoked":false}}`, clientOrgID)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch consent grants: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var results []*Result
+	seen := make(map[string]bool)
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch consent grant: %v", err)
+		}
+
+		var grant ConsentGrant
+		err = json.Unmarshal(queryResult.Value, &grant)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal consent grant: %v", err)
+		}
+		if !grant.names(clientID, companyId) || !grant.isActive(now) {
+			continue
+		}
+
+		for _, resultId := range grant.ResultIds {
+			if resultId == TranscriptConsent || seen[resultId] {
+				continue
+			}
+			seen[resultId] = true
+
+			resultBytes, err := ctx.GetStub().GetState(resultId)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read from world state: %v", err)
+			}
+			if resultBytes == nil {
+				continue
+			}
+			var result Result
+			err = json.Unmarshal(resultBytes, &result)
+			if err != nil {
+				return nil, fmt.Errorf("could not unmarshal result: %v", err)
+			}
+			results = append(results, &result)
+		}
+	}
+
+	return results, nil
+}
+
+// names reports whether the grant was given to a company identity, by its client ID or by its
+// companyId certificate attribute
+func (g *ConsentGrant) names(clientID string, companyId string) bool {
+	return g.Grantee == clientID || (companyId != "" && g.Grantee == companyId)
+}
+
+// isActive reports whether the grant is unrevoked and now lies within its validity window
+func (g *ConsentGrant) isActive(now time.Time) bool {
+	if g.Revoked {
+		return false
+	}
+	from, err := time.Parse(time.RFC3339, g.ValidFrom)
+	if err != nil || now.Before(from) {
+		return false
+	}
+	until, err := time.Parse(time.RFC3339, g.ValidUntil)
+	if err != nil || !now.Before(until) {
+		return false
+	}
+	return true
+}
+
+// authorizeStudentData allows university staff and the student themselves to see a student's consent data
+func authorizeStudentData(ctx contractapi.TransactionContextInterface, studentId string) error {
+	role, err := authorize(ctx, ActionConsentRead)
+	if err != nil {
+		return err
+	}
+
+	if role == RoleStudent {
+		callerStudentId, err := clientStudentId(ctx)
+		if err != nil {
+			return err
+		}
+		if callerStudentId != studentId {
+			return newChaincodeError(CodeForbidden, "student %s cannot view consent data of student %s", callerStudentId, studentId)
+		}
+	}
+	return nil
+}
+
+func putConsentGrant(ctx contractapi.TransactionContextInterface, key string, grant *ConsentGrant) error {
+	grantBytes, err := json.Marshal(grant)
+	if err != nil {
+		return fmt.Errorf("failed to marshal consent grant: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(key, grantBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store consent grant in world state: %v", err)
+	}
+	return nil
+}
+
+// recordConsentEvent appends an audit entry for the student; subject keeps several entries of one transaction apart
+func recordConsentEvent(ctx contractapi.TransactionContextInterface, studentId string, action string, grantId string, resultId string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	event := ConsentEvent{
+		AssetType: "ConsentEvent",
+		StudentId: studentId,
+		Action:    action,
+		GrantId:   grantId,
+		ResultId:  resultId,
+		Actor:     clientID,
+		ActorMSP:  clientOrgID,
+		Timestamp: timestamp,
+		TxId:      txID,
+	}
+
+	subject := grantId
+	if resultId != "" {
+		subject = resultId
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(consentEventObjectType, []string{studentId, txID, subject})
+	if err != nil {
+		return fmt.Errorf("could not create consent event key: %v", err)
+	}
+
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal consent event: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(key, eventBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store consent event in world state: %v", err)
+	}
+
+	// Grants and revocations are also announced to listening clients
+	eventName := ""
+	switch action {
+	case ConsentGranted:
+		eventName = "ConsentGranted"
+	case ConsentRevoked:
+		eventName = "ConsentRevoked"
+	}
+	if eventName != "" {
+		err = ctx.GetStub().SetEvent(eventName, eventBytes)
+		if err != nil {
+			return fmt.Errorf("failed to set consent event: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,638 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Object types of the composite keys used by eligibility policies
+const (
+	eligibilityPolicyObjectType     string = "eligibilityPolicy"     // (policyId)
+	eligibilityEvaluationObjectType string = "eligibilityEvaluation" // (policyId, studentId, txId)
+)
+
+// Names of the rules an eligibility policy can enforce
+const (
+	RuleResultReadable string = "resultReadable"
+	RuleActiveIssuer   string = "activeIssuer"
+	RuleNotRevoked     string = "notRevoked"
+	RuleMinPercentage  string = "minPercentage"
+	RuleRequiredStatus string = "requiredStatus"
+	RuleGraduationYear string = "graduationYear"
+	RuleRequiredCourse string = "requiredCourses"
+)
+
+// EligibilityRules are the criteria a company applies to candidates. Zero values disable a rule.
+type EligibilityRules struct {
+	MinPercentage      float64  `json:"minPercentage"`                                     // Minimum result percentage, 0 to 100
+	RequiredStatus     string   `json:"requiredStatus,omitempty" metadata:",optional"`     // Required result status, "Pass" or "Fail"
+	GraduationYearFrom int      `json:"graduationYearFrom,omitempty" metadata:",optional"` // Earliest accepted graduation year
+	GraduationYearTo   int      `json:"graduationYearTo,omitempty" metadata:",optional"`   // Latest accepted graduation year
+	RequiredCourses    []string `json:"requiredCourses,omitempty" metadata:",optional"`    // Course codes the student must have passed
+	RejectRevoked      bool     `json:"rejectRevoked"`                                     // Whether a revoked result disqualifies the candidate
+}
+
+// EligibilityPolicy is a company's named set of eligibility rules
+type EligibilityPolicy struct {
+	AssetType  string            `json:"assetType"`  // Asset type ("EligibilityPolicy")
+	PolicyId   string            `json:"policyId"`   // Unique identifier for the policy
+	CompanyMSP string            `json:"companyMsp"` // MSP of the company that owns the policy
+	Name       string            `json:"name"`       // Human readable policy name
+	Rules      *EligibilityRules `json:"rules"`      // Criteria applied by EvaluateEligibility
+	Version    int               `json:"version"`    // Incremented on every update
+	UpdatedBy  string            `json:"updatedBy"`  // Client identity that last wrote the policy
+	UpdatedAt  string            `json:"updatedAt"`  // Transaction timestamp of the last write (RFC3339)
+}
+
+// RuleOutcome is the result of applying one rule
+type RuleOutcome struct {
+	Rule   string `json:"rule"`                                  // Rule name
+	Passed bool   `json:"passed"`                                // Whether the candidate satisfies the rule
+	Detail string `json:"detail,omitempty" metadata:",optional"` // What was compared, kept in the company's offer collection
+}
+
+// EligibilityEvaluation records the outcome of applying a policy to a student. The full evaluation
+// is kept in the offer collection of the evaluating company; public state only keeps which rules
+// passed, without the percentages and grades they were compared against.
+type EligibilityEvaluation struct {
+	AssetType      string         `json:"assetType"`                               // Asset type ("EligibilityEvaluation")
+	PolicyId       string         `json:"policyId"`                                // Policy applied
+	PolicyVersion  int            `json:"policyVersion"`                           // Version of the policy applied
+	StudentId      string         `json:"studentId"`                               // Student evaluated
+	ResultId       string         `json:"resultId,omitempty" metadata:",optional"` // Result the result rules were applied to
+	Eligible       bool           `json:"eligible"`                                // Whether every rule passed
+	Passed         []*RuleOutcome `json:"passed"`                                  // Rules the student satisfies
+	Failed         []*RuleOutcome `json:"failed"`                                  // Rules the student does not satisfy
+	EvaluatedBy    string         `json:"evaluatedBy"`                             // Client identity that ran the evaluation
+	EvaluatedByMSP string         `json:"evaluatedByMsp"`                          // MSP of that identity
+	EvaluatedAt    string         `json:"evaluatedAt"`                             // Transaction timestamp (RFC3339)
+	TxId           string         `json:"txId"`                                    // Transaction ID
+}
+
+// CreateEligibilityPolicy stores a new policy for the calling company. rules is a JSON object of
+// EligibilityRules, e.g. {"minPercentage":60,"requiredStatus":"Pass","rejectRevoked":true}.
+func (o *OfferContract) CreateEligibilityPolicy(ctx contractapi.TransactionContextInterface, policyId string, name string, rules string) (string, error) {
+	_, err := authorize(ctx, ActionPolicyManage)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(policyId) == "" {
+		return "", newChaincodeError(CodeInvalidArgument, "policyId cannot be empty")
+	}
+
+	existing, err := getEligibilityPolicy(ctx, policyId)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return "", newChaincodeError(CodeConflict, "the eligibility policy %s already exists", policyId)
+	}
+
+	parsedRules, err := parseEligibilityRules(rules)
+	if err != nil {
+		return "", err
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+
+	policy := &EligibilityPolicy{
+		AssetType:  "EligibilityPolicy",
+		PolicyId:   policyId,
+		CompanyMSP: clientOrgID,
+		Name:       name,
+		Rules:      parsedRules,
+	}
+	err = putEligibilityPolicy(ctx, policy)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Eligibility policy %v created", policyId), nil
+}
+
+// UpdateEligibilityPolicy replaces the rules of a policy owned by the calling company
+func (o *OfferContract) UpdateEligibilityPolicy(ctx contractapi.TransactionContextInterface, policyId string, rules string) (string, error) {
+	_, err := authorize(ctx, ActionPolicyManage)
+	if err != nil {
+		return "", err
+	}
+
+	policy, err := getOwnedEligibilityPolicy(ctx, policyId)
+	if err != nil {
+		return "", err
+	}
+
+	policy.Rules, err = parseEligibilityRules(rules)
+	if err != nil {
+		return "", err
+	}
+	err = putEligibilityPolicy(ctx, policy)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Eligibility policy %v updated to version %d", policyId, policy.Version), nil
+}
+
+// ReadEligibilityPolicy retrieves a policy owned by the calling company
+func (o *OfferContract) ReadEligibilityPolicy(ctx contractapi.TransactionContextInterface, policyId string) (*EligibilityPolicy, error) {
+	_, err := authorize(ctx, ActionPolicyManage)
+	if err != nil {
+		return nil, err
+	}
+
+	return getOwnedEligibilityPolicy(ctx, policyId)
+}
+
+// GetEligibilityPolicies lists the policies of the calling company
+func (o *OfferContract) GetEligibilityPolicies(ctx contractapi.TransactionContextInterface) ([]*EligibilityPolicy, error) {
+	_, err := authorize(ctx, ActionPolicyManage)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+
+	policiesIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(eligibilityPolicyObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch eligibility policies: %v", err)
+	}
+	defer policiesIterator.Close()
+
+	var policies []*EligibilityPolicy
+	for policiesIterator.HasNext() {
+		queryResult, err := policiesIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch eligibility policy: %v", err)
+		}
+
+		var policy EligibilityPolicy
+		err = json.Unmarshal(queryResult.Value, &policy)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal eligibility policy: %v", err)
+		}
+		if policy.CompanyMSP == clientOrgID {
+			policies = append(policies, &policy)
+		}
+	}
+
+	return policies, nil
+}
+
+// EvaluateEligibility applies a company's policy to a student and records the evaluation.
+// Result rules are applied to every result the company may read under consent and the
+// best matching result is reported; graduation year and course rules use the transcript
+// when the student granted TranscriptConsent, and fail as not readable otherwise.
+func (o *OfferContract) EvaluateEligibility(ctx contractapi.TransactionContextInterface, studentId string, policyId string) (*EligibilityEvaluation, error) {
+	_, err := authorize(ctx, ActionPolicyEvaluate)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := getOwnedEligibilityPolicy(ctx, policyId)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := getResultsByStudent(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+	readable, err := readableResultsWithMarks(ctx, results)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pick the result with the fewest failed rules, then the highest percentage
+	var resultId string
+	var resultOutcomes []*RuleOutcome
+	bestFailures := -1
+	bestPercentage := math.Inf(-1)
+	for _, result := range readable {
+		outcomes, err := applyResultRules(ctx, policy.Rules, result)
+		if err != nil {
+			return nil, err
+		}
+		failures := countFailed(outcomes)
+		if bestFailures == -1 || failures < bestFailures || (failures == bestFailures && result.Percentage > bestPercentage) {
+			resultId = result.ResultId
+			resultOutcomes = outcomes
+			bestFailures = failures
+			bestPercentage = result.Percentage
+		}
+	}
+	if resultId == "" {
+		resultOutcomes = []*RuleOutcome{{
+			Rule:   RuleResultReadable,
+			Passed: false,
+			Detail: "no results of the student are readable under an active consent",
+		}}
+	}
+
+	transcriptOutcomes, err := applyReadableTranscriptRules(ctx, policy.Rules, studentId)
+	if err != nil {
+		return nil, err
+	}
+	outcomes := append(resultOutcomes, transcriptOutcomes...)
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	evaluatedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation := &EligibilityEvaluation{
+		AssetType:      "EligibilityEvaluation",
+		PolicyId:       policyId,
+		PolicyVersion:  policy.Version,
+		StudentId:      studentId,
+		ResultId:       resultId,
+		Passed:         []*RuleOutcome{},
+		Failed:         []*RuleOutcome{},
+		EvaluatedBy:    clientID,
+		EvaluatedByMSP: clientOrgID,
+		EvaluatedAt:    evaluatedAt,
+		TxId:           ctx.GetStub().GetTxID(),
+	}
+	for _, outcome := range outcomes {
+		if outcome.Passed {
+			evaluation.Passed = append(evaluation.Passed, outcome)
+		} else {
+			evaluation.Failed = append(evaluation.Failed, outcome)
+		}
+	}
+	evaluation.Eligible = len(evaluation.Failed) == 0
+
+	err = putEligibilityEvaluation(ctx, evaluation)
+	if err != nil {
+		return nil, err
+	}
+
+	return evaluation, nil
+}
+
+// GetEligibilityEvaluations lists the recorded evaluations of a policy owned by the calling
+// company, optionally restricted to one student
+func (o *OfferContract) GetEligibilityEvaluations(ctx contractapi.TransactionContextInterface, policyId string, studentId string) ([]*EligibilityEvaluation, error) {
+	_, err := authorize(ctx, ActionPolicyEvaluate)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = getOwnedEligibilityPolicy(ctx, policyId)
+	if err != nil {
+		return nil, err
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+
+	attributes := []string{policyId}
+	if studentId != "" {
+		attributes = append(attributes, studentId)
+	}
+	evaluationsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(eligibilityEvaluationObjectType, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch eligibility evaluations: %v", err)
+	}
+	defer evaluationsIterator.Close()
+
+	var evaluations []*EligibilityEvaluation
+	for evaluationsIterator.HasNext() {
+		queryResult, err := evaluationsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch eligibility evaluation: %v", err)
+		}
+
+		// Full evaluations from the company's collection take precedence over the public outcome
+		evaluationBytes := queryResult.Value
+		privateBytes, err := ctx.GetStub().GetPrivateData(offerCollectionName(clientOrgID), queryResult.Key)
+		if err != nil {
+			return nil, fmt.Errorf("could not read eligibility evaluation from the private collection: %v", err)
+		}
+		if privateBytes != nil {
+			evaluationBytes = privateBytes
+		}
+
+		var evaluation EligibilityEvaluation
+		err = json.Unmarshal(evaluationBytes, &evaluation)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal eligibility evaluation: %v", err)
+		}
+		evaluations = append(evaluations, &evaluation)
+	}
+
+	return evaluations, nil
+}
+
+// putEligibilityEvaluation stores an evaluation in the offer collection of the evaluating company,
+// and in public state without the details of its rule outcomes
+func putEligibilityEvaluation(ctx contractapi.TransactionContextInterface, evaluation *EligibilityEvaluation) error {
+	key, err := ctx.GetStub().CreateCompositeKey(eligibilityEvaluationObjectType, []string{evaluation.PolicyId, evaluation.StudentId, evaluation.TxId})
+	if err != nil {
+		return fmt.Errorf("could not create eligibility evaluation key: %v", err)
+	}
+	evaluationBytes, err := json.Marshal(evaluation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal eligibility evaluation: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(offerCollectionName(evaluation.EvaluatedByMSP), key, evaluationBytes)
+	if err != nil {
+		return fmt.Errorf("could not write eligibility evaluation to the private collection: %v", err)
+	}
+
+	public := *evaluation
+	public.Passed = withoutDetail(evaluation.Passed)
+	public.Failed = withoutDetail(evaluation.Failed)
+	publicBytes, err := json.Marshal(public)
+	if err != nil {
+		return fmt.Errorf("failed to marshal eligibility evaluation: %v", err)
+	}
+	err = ctx.GetStub().PutState(key, publicBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store eligibility evaluation in world state: %v", err)
+	}
+	return nil
+}
+
+// withoutDetail copies rule outcomes keeping only the rule and whether it passed
+func withoutDetail(outcomes []*RuleOutcome) []*RuleOutcome {
+	stripped := make([]*RuleOutcome, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		stripped = append(stripped, &RuleOutcome{Rule: outcome.Rule, Passed: outcome.Passed})
+	}
+	return stripped
+}
+
+// applyResultRules checks the rules that concern a single result
+func applyResultRules(ctx contractapi.TransactionContextInterface, rules *EligibilityRules, result *Result) ([]*RuleOutcome, error) {
+	active, err := isActiveInstitution(ctx, result.IssuerMSP)
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := []*RuleOutcome{{
+		Rule:   RuleActiveIssuer,
+		Passed: active,
+		Detail: fmt.Sprintf("result %s issued by %s", result.ResultId, result.IssuerMSP),
+	}}
+	if rules.RejectRevoked {
+		outcomes = append(outcomes, &RuleOutcome{
+			Rule:   RuleNotRevoked,
+			Passed: !result.Revoked,
+			Detail: fmt.Sprintf("result %s revoked: %t", result.ResultId, result.Revoked),
+		})
+	}
+	if rules.MinPercentage > 0 {
+		outcomes = append(outcomes, &RuleOutcome{
+			Rule:   RuleMinPercentage,
+			Passed: result.Percentage >= rules.MinPercentage,
+			Detail: fmt.Sprintf("%.2f%% against a minimum of %.2f%%", result.Percentage, rules.MinPercentage),
+		})
+	}
+	if rules.RequiredStatus != "" {
+		outcomes = append(outcomes, &RuleOutcome{
+			Rule:   RuleRequiredStatus,
+			Passed: result.Status == rules.RequiredStatus,
+			Detail: fmt.Sprintf("status %q, required %q", result.Status, rules.RequiredStatus),
+		})
+	}
+	return outcomes, nil
+}
+
+// applyTranscriptRules checks the graduation year window and required courses against the
+// student's course grades. The graduation year is the year of the latest recorded term.
+func applyTranscriptRules(rules *EligibilityRules, grades []*CourseGrade) []*RuleOutcome {
+	var outcomes []*RuleOutcome
+
+	if rules.GraduationYearFrom > 0 || rules.GraduationYearTo > 0 {
+		year := graduationYear(grades)
+		outcome := &RuleOutcome{Rule: RuleGraduationYear}
+		switch {
+		case year == 0:
+			outcome.Detail = "no terms with a year recorded on the transcript"
+		case rules.GraduationYearFrom > 0 && year < rules.GraduationYearFrom:
+			outcome.Detail = fmt.Sprintf("graduation year %d is before %d", year, rules.GraduationYearFrom)
+		case rules.GraduationYearTo > 0 && year > rules.GraduationYearTo:
+			outcome.Detail = fmt.Sprintf("graduation year %d is after %d", year, rules.GraduationYearTo)
+		default:
+			outcome.Passed = true
+			outcome.Detail = fmt.Sprintf("graduation year %d", year)
+		}
+		outcomes = append(outcomes, outcome)
+	}
+
+	if len(rules.RequiredCourses) > 0 {
+		passed := make(map[string]bool)
+		for _, grade := range grades {
+			if grade.GradePoints > 0 {
+				passed[grade.CourseCode] = true
+			}
+		}
+		var missing []string
+		for _, course := range rules.RequiredCourses {
+			if !passed[course] {
+				missing = append(missing, course)
+			}
+		}
+		outcome := &RuleOutcome{Rule: RuleRequiredCourse, Passed: len(missing) == 0}
+		if outcome.Passed {
+			outcome.Detail = fmt.Sprintf("passed %s", strings.Join(rules.RequiredCourses, ", "))
+		} else {
+			outcome.Detail = fmt.Sprintf("missing %s", strings.Join(missing, ", "))
+		}
+		outcomes = append(outcomes, outcome)
+	}
+
+	return outcomes
+}
+
+// applyReadableTranscriptRules checks the transcript rules of a policy when the caller may read the
+// student's course grades under authorizeTranscriptRead, and fails them as not readable otherwise.
+// The transcript is only read, and the access recorded, when the policy has transcript rules.
+func applyReadableTranscriptRules(ctx contractapi.TransactionContextInterface, rules *EligibilityRules, studentId string) ([]*RuleOutcome, error) {
+	if rules.GraduationYearFrom <= 0 && rules.GraduationYearTo <= 0 && len(rules.RequiredCourses) == 0 {
+		return nil, nil
+	}
+	grades, readable, err := readableTranscript(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+	if !readable {
+		return unreadableTranscriptRules(rules), nil
+	}
+	return applyTranscriptRules(rules, grades), nil
+}
+
+// unreadableTranscriptRules fails the transcript rules of a policy for a student whose course
+// grades the caller may not read
+func unreadableTranscriptRules(rules *EligibilityRules) []*RuleOutcome {
+	var outcomes []*RuleOutcome
+	detail := "the transcript of the student is not readable under an active consent"
+	if rules.GraduationYearFrom > 0 || rules.GraduationYearTo > 0 {
+		outcomes = append(outcomes, &RuleOutcome{Rule: RuleGraduationYear, Detail: detail})
+	}
+	if len(rules.RequiredCourses) > 0 {
+		outcomes = append(outcomes, &RuleOutcome{Rule: RuleRequiredCourse, Detail: detail})
+	}
+	return outcomes
+}
+
+// graduationYear returns the year prefix of the latest term, e.g. 2024 for "2024-SEM2", or 0
+func graduationYear(grades []*CourseGrade) int {
+	var terms []string
+	for _, grade := range grades {
+		terms = append(terms, grade.Term)
+	}
+	sort.Strings(terms)
+	for i := len(terms) - 1; i >= 0; i-- {
+		if len(terms[i]) < 4 {
+			continue
+		}
+		year, err := strconv.Atoi(terms[i][:4])
+		if err == nil {
+			return year
+		}
+	}
+	return 0
+}
+
+// countFailed counts the outcomes that did not pass
+func countFailed(outcomes []*RuleOutcome) int {
+	failed := 0
+	for _, outcome := range outcomes {
+		if !outcome.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// parseEligibilityRules decodes and validates the JSON rules of a policy
+func parseEligibilityRules(rules string) (*EligibilityRules, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(rules)))
+	decoder.DisallowUnknownFields()
+
+	var parsed EligibilityRules
+	err := decoder.Decode(&parsed)
+	if err != nil {
+		return nil, newChaincodeError(CodeInvalidArgument, "could not parse eligibility rules: %v", err)
+	}
+
+	if math.IsNaN(parsed.MinPercentage) || parsed.MinPercentage < 0 || parsed.MinPercentage > 100 {
+		return nil, newChaincodeError(CodeInvalidArgument, "minPercentage must be between 0 and 100, got %v", parsed.MinPercentage)
+	}
+	if parsed.RequiredStatus != "" && parsed.RequiredStatus != StatusPass && parsed.RequiredStatus != StatusFail {
+		return nil, newChaincodeError(CodeInvalidArgument, "requiredStatus must be %q or %q, got %q", StatusPass, StatusFail, parsed.RequiredStatus)
+	}
+	if parsed.GraduationYearFrom < 0 || parsed.GraduationYearTo < 0 {
+		return nil, newChaincodeError(CodeInvalidArgument, "graduation years cannot be negative")
+	}
+	if parsed.GraduationYearFrom > 0 && parsed.GraduationYearTo > 0 && parsed.GraduationYearFrom > parsed.GraduationYearTo {
+		return nil, newChaincodeError(CodeInvalidArgument, "graduationYearFrom %d is after graduationYearTo %d", parsed.GraduationYearFrom, parsed.GraduationYearTo)
+	}
+	for _, course := range parsed.RequiredCourses {
+		if strings.TrimSpace(course) == "" {
+			return nil, newChaincodeError(CodeInvalidArgument, "requiredCourses cannot contain empty course codes")
+		}
+	}
+
+	return &parsed, nil
+}
+
+// getOwnedEligibilityPolicy reads a policy and checks that it belongs to the caller's organization
+func getOwnedEligibilityPolicy(ctx contractapi.TransactionContextInterface, policyId string) (*EligibilityPolicy, error) {
+	policy, err := getEligibilityPolicy(ctx, policyId)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, newChaincodeError(CodeNotFound, "the eligibility policy %s does not exist", policyId)
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	if policy.CompanyMSP != clientOrgID {
+		return nil, newChaincodeError(CodeForbidden, "eligibility policy %s belongs to %s and cannot be used by %v", policyId, policy.CompanyMSP, clientOrgID)
+	}
+
+	return policy, nil
+}
+
+// getEligibilityPolicy reads a policy from the world state, returning nil if it does not exist
+func getEligibilityPolicy(ctx contractapi.TransactionContextInterface, policyId string) (*EligibilityPolicy, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(eligibilityPolicyObjectType, []string{policyId})
+	if err != nil {
+		return nil, fmt.Errorf("could not create eligibility policy key: %v", err)
+	}
+
+	policyBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if policyBytes == nil {
+		return nil, nil
+	}
+
+	var policy EligibilityPolicy
+	err = json.Unmarshal(policyBytes, &policy)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal eligibility policy: %v", err)
+	}
+
+	return &policy, nil
+}
+
+// putEligibilityPolicy bumps the policy version, stamps the writer and stores the policy
+func putEligibilityPolicy(ctx contractapi.TransactionContextInterface, policy *EligibilityPolicy) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	updatedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	policy.Version++
+	policy.UpdatedBy = clientID
+	policy.UpdatedAt = updatedAt
+
+	key, err := ctx.GetStub().CreateCompositeKey(eligibilityPolicyObjectType, []string{policy.PolicyId})
+	if err != nil {
+		return fmt.Errorf("could not create eligibility policy key: %v", err)
+	}
+	policyBytes, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal eligibility policy: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(key, policyBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store eligibility policy in world state: %v", err)
+	}
+	return nil
+}
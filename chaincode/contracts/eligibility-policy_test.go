@@ -0,0 +1,68 @@
+package contracts
+
+import (
+	"strings"
+	"testing"
+)
+
+// Client identity of every mock context, named by consent grants
+const mockClientID = "x509::CN=user1::CN=ca"
+
+func TestEvaluateEligibilityRequiresTranscriptConsent(t *testing.T) {
+	stub := newRegistryStub(t)
+	registrar := newMockContext(stub, "UniversityMSP", RoleRegistrar)
+	student := newMockContext(stub, "StudentMSP", RoleStudent)
+	recruiter := newMockContext(stub, "CompanyMSP", RoleHR)
+
+	_, err := new(ResultContract).CreateResult(registrar, "RES1", "user1", 100, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = new(ResultContract).AddCourseGrade(registrar, "user1", "2024-SEM2", "CS101", "Programming Fundamentals", 4, 9, "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = new(ConsentContract).GrantConsent(student, "G1", "CompanyMSP", mockClientID, []string{"RES1"}, "2026-12-31T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	offers := new(OfferContract)
+	_, err = offers.CreateEligibilityPolicy(recruiter, "P1", "Graduates", `{"minPercentage":60,"graduationYearFrom":2024}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a transcript grant the transcript rules are reported, not evaluated
+	evaluation, err := offers.EvaluateEligibility(recruiter, "user1", "P1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evaluation.Eligible || len(evaluation.Failed) != 1 || evaluation.Failed[0].Rule != RuleGraduationYear || !strings.Contains(evaluation.Failed[0].Detail, "not readable") {
+		t.Fatalf("expected only the graduation year rule to fail as not readable, got %+v", evaluation.Failed)
+	}
+
+	// Public state records the outcome without the compared values
+	key, _ := stub.CreateCompositeKey(eligibilityEvaluationObjectType, []string{"P1", "user1", "tx1"})
+	if public := string(stub.state[key]); public == "" || strings.Contains(public, "80.00") || strings.Contains(public, "detail") {
+		t.Fatalf("expected a public evaluation without details, got %s", public)
+	}
+	if private := string(stub.private[offerCollectionName("CompanyMSP")][key]); !strings.Contains(private, "80.00") {
+		t.Fatalf("expected the full evaluation in the company's collection, got %s", private)
+	}
+
+	_, err = new(ConsentContract).GrantConsent(student, "G2", "CompanyMSP", mockClientID, []string{TranscriptConsent}, "2026-12-31T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluation, err = offers.EvaluateEligibility(recruiter, "user1", "P1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !evaluation.Eligible {
+		t.Fatalf("expected the student to be eligible under a transcript grant, got %+v", evaluation.Failed)
+	}
+	accessed, _ := stub.CreateCompositeKey(consentEventObjectType, []string{"user1", "tx1", TranscriptConsent})
+	if stub.state[accessed] == nil {
+		t.Fatal("expected the transcript read to be recorded as a consent event")
+	}
+}
@@ -0,0 +1,135 @@
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Object type of the composite key (proposalId, mspId) for approvals of governance changes
+const approvalObjectType string = "approval"
+
+// GovernanceApproval records that a governing organization approved a change. A change is applied
+// once a majority of the governing organizations approved the same action with the same arguments,
+// so that no single consortium member can change the registry on its own.
+type GovernanceApproval struct {
+	AssetType  string   `json:"assetType"`  // Asset type ("GovernanceApproval")
+	ProposalId string   `json:"proposalId"` // Hex SHA-256 of the change and its arguments
+	Change     string   `json:"change"`     // Name of the approved transaction
+	Args       []string `json:"args"`       // Arguments of the approved transaction
+	MspId      string   `json:"mspId"`      // Approving governing organization
+	ApprovedBy string   `json:"approvedBy"` // Governor identity that approved
+	ApprovedAt string   `json:"approvedAt"` // Transaction timestamp of the approval (RFC3339)
+}
+
+// governingMSPs lists the distinct organizations holding a governor rule, in policy order
+func governingMSPs() []string {
+	var mspIds []string
+	seen := make(map[string]bool)
+	for _, rule := range consortiumGovernors {
+		if !seen[rule.MSP] {
+			seen[rule.MSP] = true
+			mspIds = append(mspIds, rule.MSP)
+		}
+	}
+	return mspIds
+}
+
+// approvalThreshold is the number of governing organizations that must approve a change
+func approvalThreshold() int {
+	return len(governingMSPs())/2 + 1
+}
+
+// proposalID identifies a change by its transaction name and arguments
+func proposalID(change string, args []string) (string, error) {
+	proposalBytes, err := json.Marshal(append([]string{change}, args...))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal proposal: %v", err)
+	}
+	hash := sha256.Sum256(proposalBytes)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// approveChange records the approval of the calling governor's organization for a change and
+// returns the number of organizations that approved it so far. Once the threshold is reached the
+// approvals are cleared, so the same change can be proposed again later, and the caller applies it.
+// Callers authorize the governor first.
+func approveChange(ctx contractapi.TransactionContextInterface, change string, args ...string) (int, error) {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return 0, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return 0, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	proposalId, err := proposalID(change, args)
+	if err != nil {
+		return 0, err
+	}
+
+	approvals := 0
+	var keys []string
+	for _, mspId := range governingMSPs() {
+		key, err := ctx.GetStub().CreateCompositeKey(approvalObjectType, []string{proposalId, mspId})
+		if err != nil {
+			return 0, fmt.Errorf("could not create approval key: %v", err)
+		}
+		approvalBytes, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read from world state: %v", err)
+		}
+		if approvalBytes != nil {
+			if mspId == clientOrgID {
+				return 0, newChaincodeError(CodeConflict, "%s has already approved %s", clientOrgID, change)
+			}
+			approvals++
+			keys = append(keys, key)
+		}
+	}
+	approvals++
+
+	if approvals >= approvalThreshold() {
+		for _, key := range keys {
+			err = ctx.GetStub().DelState(key)
+			if err != nil {
+				return 0, fmt.Errorf("failed to delete approval: %v", err)
+			}
+		}
+		return approvals, nil
+	}
+
+	approvedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return 0, err
+	}
+	approvalBytes, err := json.Marshal(GovernanceApproval{
+		AssetType:  "GovernanceApproval",
+		ProposalId: proposalId,
+		Change:     change,
+		Args:       args,
+		MspId:      clientOrgID,
+		ApprovedBy: clientID,
+		ApprovedAt: approvedAt,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal approval: %v", err)
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(approvalObjectType, []string{proposalId, clientOrgID})
+	if err != nil {
+		return 0, fmt.Errorf("could not create approval key: %v", err)
+	}
+	err = ctx.GetStub().PutState(key, approvalBytes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to store approval in world state: %v", err)
+	}
+	return approvals, nil
+}
+
+// pendingApprovalMessage reports a change still waiting for approvals
+func pendingApprovalMessage(change string, approvals int) string {
+	return fmt.Sprintf("Approval of %v recorded: %d of %d governing organizations have approved", change, approvals, approvalThreshold())
+}
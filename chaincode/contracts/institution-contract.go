@@ -0,0 +1,331 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// InstitutionRegistryContract maintains the accredited institutions allowed to issue results
+type InstitutionRegistryContract struct {
+	contractapi.Contract
+}
+
+// Object type of the composite key (mspId) for registered institutions
+const institutionObjectType string = "institution"
+
+// Accreditation status values of an institution
+const (
+	InstitutionActive    string = "ACTIVE"
+	InstitutionSuspended string = "SUSPENDED"
+	InstitutionRemoved   string = "REMOVED"
+)
+
+// Institution is an accredited issuer of results identified by its MSP ID
+type Institution struct {
+	AssetType    string `json:"assetType"`                                   // Asset type ("Institution")
+	MspId        string `json:"mspId"`                                       // MSP ID the institution signs with
+	Name         string `json:"name"`                                        // Official name of the institution
+	Status       string `json:"status"`                                      // ACTIVE, SUSPENDED or REMOVED
+	ValidFrom    string `json:"validFrom"`                                   // Start of accreditation (RFC3339)
+	ValidTo      string `json:"validTo"`                                     // End of accreditation (RFC3339)
+	StatusReason string `json:"statusReason,omitempty" metadata:",optional"` // Reason for the last suspension or removal
+	UpdatedBy    string `json:"updatedBy"`                                   // Governor identity that last changed the entry
+	UpdatedAt    string `json:"updatedAt"`                                   // Transaction timestamp of the last change (RFC3339)
+}
+
+// AddInstitution registers an accredited institution for the given validity window (RFC3339) once a
+// majority of the governing organizations called it with the same arguments
+func (i *InstitutionRegistryContract) AddInstitution(ctx contractapi.TransactionContextInterface, mspId string, name string, validFrom string, validTo string) (string, error) {
+	if strings.TrimSpace(mspId) == "" || strings.TrimSpace(name) == "" {
+		return "", newChaincodeError(CodeInvalidArgument, "mspId and name cannot be empty")
+	}
+
+	from, err := time.Parse(time.RFC3339, validFrom)
+	if err != nil {
+		return "", newChaincodeError(CodeInvalidArgument, "validFrom must be an RFC3339 timestamp: %v", err)
+	}
+	to, err := time.Parse(time.RFC3339, validTo)
+	if err != nil {
+		return "", newChaincodeError(CodeInvalidArgument, "validTo must be an RFC3339 timestamp: %v", err)
+	}
+	if !to.After(from) {
+		return "", newChaincodeError(CodeInvalidArgument, "validTo must be after validFrom")
+	}
+
+	_, err = authorize(ctx, ActionInstitutionGovern)
+	if err != nil {
+		return "", err
+	}
+
+	existing, err := getInstitution(ctx, mspId)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil && existing.Status != InstitutionRemoved {
+		return "", newChaincodeError(CodeConflict, "institution %s is already registered", mspId)
+	}
+
+	// Results of the institution can only be stored once its private collections are defined
+	err = checkInstitutionCollections(ctx, mspId)
+	if err != nil {
+		return "", err
+	}
+
+	approvals, err := approveChange(ctx, "AddInstitution", mspId, name, validFrom, validTo)
+	if err != nil {
+		return "", err
+	}
+	if approvals < approvalThreshold() {
+		return pendingApprovalMessage("the registration of "+mspId, approvals), nil
+	}
+
+	institution := Institution{
+		AssetType: "Institution",
+		MspId:     mspId,
+		Name:      name,
+		Status:    InstitutionActive,
+		ValidFrom: from.UTC().Format(time.RFC3339),
+		ValidTo:   to.UTC().Format(time.RFC3339),
+	}
+
+	err = putInstitution(ctx, &institution)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Institution %v registered as %v", name, mspId), nil
+}
+
+// Status changes of institutions, like registrations, apply once a majority of the governing
+// organizations requested them with the same arguments.
+
+// SuspendInstitution stops an institution from issuing results until it is reinstated
+func (i *InstitutionRegistryContract) SuspendInstitution(ctx contractapi.TransactionContextInterface, mspId string, reason string) (string, error) {
+	return i.setInstitutionStatus(ctx, mspId, InstitutionSuspended, reason)
+}
+
+// ReinstateInstitution reactivates a suspended institution
+func (i *InstitutionRegistryContract) ReinstateInstitution(ctx contractapi.TransactionContextInterface, mspId string) (string, error) {
+	return i.setInstitutionStatus(ctx, mspId, InstitutionActive, "")
+}
+
+// RemoveInstitution withdraws accreditation; the entry is kept so issued results still name their issuer
+func (i *InstitutionRegistryContract) RemoveInstitution(ctx contractapi.TransactionContextInterface, mspId string, reason string) (string, error) {
+	return i.setInstitutionStatus(ctx, mspId, InstitutionRemoved, reason)
+}
+
+// ReadInstitution retrieves a registered institution
+func (i *InstitutionRegistryContract) ReadInstitution(ctx contractapi.TransactionContextInterface, mspId string) (*Institution, error) {
+	_, err := authorize(ctx, ActionInstitutionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	institution, err := getInstitution(ctx, mspId)
+	if err != nil {
+		return nil, err
+	}
+	if institution == nil {
+		return nil, newChaincodeError(CodeNotFound, "the institution %s is not registered", mspId)
+	}
+
+	return institution, nil
+}
+
+// GetAllInstitutions lists every institution in the registry
+func (i *InstitutionRegistryContract) GetAllInstitutions(ctx contractapi.TransactionContextInterface) ([]*Institution, error) {
+	_, err := authorize(ctx, ActionInstitutionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	institutionsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(institutionObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch institutions: %v", err)
+	}
+	defer institutionsIterator.Close()
+
+	var institutions []*Institution
+	for institutionsIterator.HasNext() {
+		queryResult, err := institutionsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch institution: %v", err)
+		}
+
+		var institution Institution
+		err = json.Unmarshal(queryResult.Value, &institution)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal institution: %v", err)
+		}
+		institutions = append(institutions, &institution)
+	}
+
+	return institutions, nil
+}
+
+// GetPendingApprovals lists the approvals of governance changes still below the threshold
+func (i *InstitutionRegistryContract) GetPendingApprovals(ctx contractapi.TransactionContextInterface) ([]*GovernanceApproval, error) {
+	_, err := authorize(ctx, ActionInstitutionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	approvalsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(approvalObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch approvals: %v", err)
+	}
+	defer approvalsIterator.Close()
+
+	var approvals []*GovernanceApproval
+	for approvalsIterator.HasNext() {
+		queryResult, err := approvalsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch approval: %v", err)
+		}
+
+		var approval GovernanceApproval
+		err = json.Unmarshal(queryResult.Value, &approval)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal approval: %v", err)
+		}
+		approvals = append(approvals, &approval)
+	}
+
+	return approvals, nil
+}
+
+func (i *InstitutionRegistryContract) setInstitutionStatus(ctx contractapi.TransactionContextInterface, mspId string, status string, reason string) (string, error) {
+	if status != InstitutionActive && strings.TrimSpace(reason) == "" {
+		return "", newChaincodeError(CodeInvalidArgument, "a reason is required to change the status of an institution to %s", status)
+	}
+
+	_, err := authorize(ctx, ActionInstitutionGovern)
+	if err != nil {
+		return "", err
+	}
+
+	institution, err := getInstitution(ctx, mspId)
+	if err != nil {
+		return "", err
+	}
+	if institution == nil {
+		return "", newChaincodeError(CodeNotFound, "the institution %s is not registered", mspId)
+	}
+	if institution.Status == InstitutionRemoved {
+		return "", newChaincodeError(CodeConflict, "institution %s has been removed and must be registered again", mspId)
+	}
+	if institution.Status == status {
+		return "", newChaincodeError(CodeConflict, "institution %s is already %s", mspId, status)
+	}
+
+	approvals, err := approveChange(ctx, "SetInstitutionStatus", mspId, status, reason)
+	if err != nil {
+		return "", err
+	}
+	if approvals < approvalThreshold() {
+		return pendingApprovalMessage("status "+status+" of "+mspId, approvals), nil
+	}
+
+	institution.Status = status
+	institution.StatusReason = reason
+
+	err = putInstitution(ctx, institution)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Institution %v is now %v", mspId, status), nil
+}
+
+// isActiveInstitution reports whether mspId is registered, active and within its accreditation window
+func isActiveInstitution(ctx contractapi.TransactionContextInterface, mspId string) (bool, error) {
+	institution, err := getInstitution(ctx, mspId)
+	if err != nil || institution == nil || institution.Status != InstitutionActive {
+		return false, err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	from, err := time.Parse(time.RFC3339, institution.ValidFrom)
+	if err != nil {
+		return false, fmt.Errorf("institution %s has an invalid validFrom: %v", mspId, err)
+	}
+	to, err := time.Parse(time.RFC3339, institution.ValidTo)
+	if err != nil {
+		return false, fmt.Errorf("institution %s has an invalid validTo: %v", mspId, err)
+	}
+
+	return !now.Before(from) && now.Before(to), nil
+}
+
+// checkInstitutionCollections fails unless the marks and disclosure collections of an institution
+// are part of the chaincode definition. Peers reject reads of undefined collections.
+func checkInstitutionCollections(ctx contractapi.TransactionContextInterface, mspId string) error {
+	for _, collection := range []string{marksCollectionName(mspId), disclosureCollectionName(mspId)} {
+		_, err := ctx.GetStub().GetPrivateDataHash(collection, mspId)
+		if err != nil {
+			return newChaincodeError(CodeInvalidArgument, "collection %s must be added to the chaincode definition before institution %s is registered: %v", collection, mspId, err)
+		}
+	}
+	return nil
+}
+
+// getInstitution reads a registry entry, returning nil when the MSP is not registered
+func getInstitution(ctx contractapi.TransactionContextInterface, mspId string) (*Institution, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(institutionObjectType, []string{mspId})
+	if err != nil {
+		return nil, fmt.Errorf("could not create institution key: %v", err)
+	}
+
+	institutionBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if institutionBytes == nil {
+		return nil, nil
+	}
+
+	var institution Institution
+	err = json.Unmarshal(institutionBytes, &institution)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal institution: %v", err)
+	}
+
+	return &institution, nil
+}
+
+// putInstitution stamps the governor identity and timestamp on an entry and stores it
+func putInstitution(ctx contractapi.TransactionContextInterface, institution *Institution) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	updatedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	institution.UpdatedBy = clientID
+	institution.UpdatedAt = updatedAt
+
+	key, err := ctx.GetStub().CreateCompositeKey(institutionObjectType, []string{institution.MspId})
+	if err != nil {
+		return fmt.Errorf("could not create institution key: %v", err)
+	}
+
+	institutionBytes, err := json.Marshal(institution)
+	if err != nil {
+		return fmt.Errorf("failed to marshal institution: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(key, institutionBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store institution in world state: %v", err)
+	}
+	return nil
+}
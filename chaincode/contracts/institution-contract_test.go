@@ -0,0 +1,65 @@
+package contracts
+
+import (
+	"testing"
+)
+
+func TestAddInstitutionRequiresMajorityOfGovernors(t *testing.T) {
+	stub := newMockStub()
+	stub.defineCollections(marksCollectionName("CompanyMSP"), disclosureCollectionName("CompanyMSP"))
+	registry := new(InstitutionRegistryContract)
+	args := []string{"CompanyMSP", "Company University", "2025-01-01T00:00:00Z", "2030-01-01T00:00:00Z"}
+
+	// A single foreign governor cannot register its own organization as an issuer
+	_, err := registry.AddInstitution(newMockContext(stub, "CompanyMSP", RoleGovernor), args[0], args[1], args[2], args[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	institution, err := getInstitution(newMockContext(stub, "CompanyMSP", RoleGovernor), "CompanyMSP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if institution != nil {
+		t.Fatal("expected CompanyMSP to stay unregistered after a single approval")
+	}
+	_, err = authorize(newMockContext(stub, "CompanyMSP", RoleRegistrar), ActionResultCreate)
+	if err == nil {
+		t.Fatal("expected CompanyMSP registrars to stay unable to create results")
+	}
+
+	// Approving again from the same organization does not count twice
+	_, err = registry.AddInstitution(newMockContext(stub, "CompanyMSP", RoleGovernor), args[0], args[1], args[2], args[3])
+	if err == nil {
+		t.Fatal("expected a second approval from CompanyMSP to be rejected")
+	}
+
+	// A second organization approving different arguments does not complete the proposal
+	_, err = registry.AddInstitution(newMockContext(stub, "StudentMSP", RoleGovernor), args[0], args[1], args[2], "2040-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	institution, _ = getInstitution(newMockContext(stub, "StudentMSP", RoleGovernor), "CompanyMSP")
+	if institution != nil {
+		t.Fatal("expected approvals of different arguments to be counted separately")
+	}
+
+	// A majority approving the same registration applies it
+	_, err = registry.AddInstitution(newMockContext(stub, "UniversityMSP", RoleGovernor), args[0], args[1], args[2], args[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = authorize(newMockContext(stub, "CompanyMSP", RoleRegistrar), ActionResultCreate)
+	if err != nil {
+		t.Fatalf("expected CompanyMSP to be registered after a majority approved: %v", err)
+	}
+}
+
+func TestAddInstitutionRequiresCollections(t *testing.T) {
+	stub := newMockStub()
+	registry := new(InstitutionRegistryContract)
+
+	_, err := registry.AddInstitution(newMockContext(stub, "UniversityMSP", RoleGovernor), "OtherUniversityMSP", "Other University", "2025-01-01T00:00:00Z", "2030-01-01T00:00:00Z")
+	if err == nil {
+		t.Fatal("expected registration without private collections to fail")
+	}
+}
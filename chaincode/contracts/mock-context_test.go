@@ -0,0 +1,265 @@
+package contracts
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// mockStub is an in-memory world state and set of private collections. Stub methods the tests do
+// not need are left to the embedded interface and panic when called.
+type mockStub struct {
+	shim.ChaincodeStubInterface
+	state     map[string][]byte
+	private   map[string]map[string][]byte
+	transient map[string][]byte
+	events    map[string][]byte
+	timestamp time.Time
+}
+
+// newMockStub returns a stub with the private collections of collection-config.json
+func newMockStub() *mockStub {
+	stub := &mockStub{
+		state:     make(map[string][]byte),
+		private:   make(map[string]map[string][]byte),
+		transient: map[string][]byte{resultSaltKey: []byte("0123456789abcdef0123456789abcdef")},
+		events:    make(map[string][]byte),
+		timestamp: time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC),
+	}
+	stub.defineCollections("Offers", "offers_CompanyMSP", marksCollectionName("UniversityMSP"), disclosureCollectionName("UniversityMSP"))
+	return stub
+}
+
+func (s *mockStub) defineCollections(collections ...string) {
+	for _, collection := range collections {
+		s.private[collection] = make(map[string][]byte)
+	}
+}
+
+func (s *mockStub) GetState(key string) ([]byte, error) {
+	return s.state[key], nil
+}
+
+func (s *mockStub) PutState(key string, value []byte) error {
+	s.state[key] = value
+	return nil
+}
+
+func (s *mockStub) DelState(key string) error {
+	delete(s.state, key)
+	return nil
+}
+
+func (s *mockStub) collection(name string) (map[string][]byte, error) {
+	collection, ok := s.private[name]
+	if !ok {
+		return nil, fmt.Errorf("collection %s could not be found", name)
+	}
+	return collection, nil
+}
+
+func (s *mockStub) GetPrivateData(collection string, key string) ([]byte, error) {
+	data, err := s.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	return data[key], nil
+}
+
+func (s *mockStub) GetPrivateDataHash(collection string, key string) ([]byte, error) {
+	data, err := s.collection(collection)
+	if err != nil || data[key] == nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data[key])
+	return hash[:], nil
+}
+
+func (s *mockStub) PutPrivateData(collection string, key string, value []byte) error {
+	data, err := s.collection(collection)
+	if err != nil {
+		return err
+	}
+	data[key] = value
+	return nil
+}
+
+func (s *mockStub) DelPrivateData(collection string, key string) error {
+	data, err := s.collection(collection)
+	if err != nil {
+		return err
+	}
+	delete(data, key)
+	return nil
+}
+
+func (s *mockStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	key := "\x00" + objectType + "\x00"
+	for _, attribute := range attributes {
+		key += attribute + "\x00"
+	}
+	return key, nil
+}
+
+func (s *mockStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	parts := strings.Split(strings.Trim(compositeKey, "\x00"), "\x00")
+	return parts[0], parts[1:], nil
+}
+
+func (s *mockStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, _ := s.CreateCompositeKey(objectType, attributes)
+	return newMockIterator(s.state, func(key string, _ []byte) bool { return strings.HasPrefix(key, prefix) }), nil
+}
+
+func (s *mockStub) GetPrivateDataByPartialCompositeKey(collection string, objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	data, err := s.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	prefix, _ := s.CreateCompositeKey(objectType, attributes)
+	return newMockIterator(data, func(key string, _ []byte) bool { return strings.HasPrefix(key, prefix) }), nil
+}
+
+// GetQueryResult supports the CouchDB selectors of the contracts: fields compared for equality and
+// "$elemMatch" with "$eq" on arrays
+func (s *mockStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	var parsed struct {
+		Selector map[string]interface{} `json:"selector"`
+	}
+	err := json.Unmarshal([]byte(query), &parsed)
+	if err != nil {
+		return nil, err
+	}
+	return newMockIterator(s.state, func(_ string, value []byte) bool {
+		var document map[string]interface{}
+		if json.Unmarshal(value, &document) != nil {
+			return false
+		}
+		for field, condition := range parsed.Selector {
+			operators, _ := condition.(map[string]interface{})
+			if match, ok := operators["$elemMatch"]; ok {
+				elements, _ := document[field].([]interface{})
+				found := false
+				for _, element := range elements {
+					found = found || reflect.DeepEqual(element, match.(map[string]interface{})["$eq"])
+				}
+				if !found {
+					return false
+				}
+			} else if !reflect.DeepEqual(document[field], condition) {
+				return false
+			}
+		}
+		return true
+	}), nil
+}
+
+func (s *mockStub) SetEvent(name string, payload []byte) error {
+	s.events[name] = payload
+	return nil
+}
+
+func (s *mockStub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+func (s *mockStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.timestamp), nil
+}
+
+func (s *mockStub) GetTxID() string {
+	return "tx1"
+}
+
+// mockIterator iterates over a snapshot of matching keys in key order
+type mockIterator struct {
+	results []*queryresult.KV
+}
+
+func newMockIterator(data map[string][]byte, match func(key string, value []byte) bool) *mockIterator {
+	var keys []string
+	for key, value := range data {
+		if match(key, value) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	iterator := &mockIterator{}
+	for _, key := range keys {
+		iterator.results = append(iterator.results, &queryresult.KV{Key: key, Value: data[key]})
+	}
+	return iterator
+}
+
+func (i *mockIterator) HasNext() bool {
+	return len(i.results) > 0
+}
+
+func (i *mockIterator) Next() (*queryresult.KV, error) {
+	if len(i.results) == 0 {
+		return nil, fmt.Errorf("iterator exhausted")
+	}
+	next := i.results[0]
+	i.results = i.results[1:]
+	return next, nil
+}
+
+func (i *mockIterator) Close() error {
+	return nil
+}
+
+// mockIdentity is a client identity of an MSP with certificate attributes
+type mockIdentity struct {
+	mspId      string
+	attributes map[string]string
+}
+
+func (i *mockIdentity) GetID() (string, error) {
+	return "x509::CN=" + i.attributes["hf.EnrollmentID"] + "::CN=ca", nil
+}
+
+func (i *mockIdentity) GetMSPID() (string, error) {
+	return i.mspId, nil
+}
+
+func (i *mockIdentity) GetAttributeValue(name string) (string, bool, error) {
+	value, found := i.attributes[name]
+	return value, found, nil
+}
+
+func (i *mockIdentity) AssertAttributeValue(name string, value string) error {
+	if i.attributes[name] != value {
+		return fmt.Errorf("attribute %s is not %s", name, value)
+	}
+	return nil
+}
+
+func (i *mockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return nil, nil
+}
+
+var _ cid.ClientIdentity = (*mockIdentity)(nil)
+
+// newMockContext returns a transaction context for a caller of an MSP with a role attribute.
+// An empty role leaves the attribute out of the certificate.
+func newMockContext(stub *mockStub, mspId string, role string) contractapi.TransactionContextInterface {
+	attributes := map[string]string{"hf.EnrollmentID": "user1"}
+	if role != "" {
+		attributes["role"] = role
+	}
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(&mockIdentity{mspId: mspId, attributes: attributes})
+	return ctx
+}
@@ -0,0 +1,222 @@
+package contracts
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Object type of the composite key (offerId) for public offer commitments
+const offerCommitmentObjectType string = "offerCommitment"
+
+// OfferCommitment is the public, never purged proof of an offer's terms
+type OfferCommitment struct {
+	AssetType  string `json:"assetType"`  // Asset type ("OfferCommitment")
+	OfferId    string `json:"offerId"`    // Committed offer
+	CompanyMSP string `json:"companyMsp"` // MSP of the issuing company
+	Hash       string `json:"hash"`       // Hex SHA-256 of the offer nonce followed by the canonical offer JSON
+	IssuedAt   string `json:"issuedAt"`   // Time the offer was issued (RFC3339)
+	TxId       string `json:"txId"`       // Transaction that wrote the commitment
+}
+
+// OfferCommitmentVerification is the answer to VerifyOfferCommitment
+type OfferCommitmentVerification struct {
+	OfferId    string `json:"offerId"`                                   // Offer the JSON claims to be
+	Matches    bool   `json:"matches"`                                   // Whether the JSON matches the commitment
+	Hash       string `json:"hash"`                                      // Hash computed from the supplied JSON
+	CompanyMSP string `json:"companyMsp,omitempty" metadata:",optional"` // Issuing company, when a commitment exists
+	IssuedAt   string `json:"issuedAt,omitempty" metadata:",optional"`   // Issue time, when a commitment exists
+	Reason     string `json:"reason"`                                    // Short explanation of the outcome
+}
+
+// canonicalOffer holds the terms covered by a commitment, in a fixed field order. Lifecycle
+// fields such as the status are left out so the proof stays valid as the offer moves on. The
+// student, result and revision are omitted when empty so that commitments of legacy offers still match.
+type canonicalOffer struct {
+	OfferId       string      `json:"offerId"`
+	CompanyMSP    string      `json:"companyMsp"`
+	CompanyName   string      `json:"companyName"`
+	StudentId     string      `json:"studentId,omitempty"`
+	ResultId      string      `json:"resultId,omitempty"`
+	Name          string      `json:"name"`
+	Email         string      `json:"email"`
+	Ctc           interface{} `json:"ctc"`
+	DateOfJoining string      `json:"dateOfJoining"`
+	DateOfRelease string      `json:"dateOfRelease"`
+	IssuedAt      string      `json:"issuedAt"`
+	Revision      int         `json:"revision,omitempty"`
+}
+
+// VerifyOfferCommitment checks an offer JSON, as returned by ReadOffer, against the public
+// commitment of the offer. The JSON must carry the offer's nonce, which only its parties hold. It
+// works after the private terms have been purged.
+func (o *OfferContract) VerifyOfferCommitment(ctx contractapi.TransactionContextInterface, offerJSON string) (*OfferCommitmentVerification, error) {
+	_, err := authorize(ctx, ActionOfferCommitment)
+	if err != nil {
+		return nil, err
+	}
+
+	var offer Offer
+	err = json.Unmarshal([]byte(offerJSON), &offer)
+	if err != nil {
+		return nil, newChaincodeError(CodeInvalidArgument, "could not parse offer JSON: %v", err)
+	}
+	if offer.OfferId == "" {
+		return nil, newChaincodeError(CodeInvalidArgument, "the offer JSON does not carry an offerId")
+	}
+
+	hash, err := offerHash(&offer)
+	if err != nil {
+		return nil, err
+	}
+	verification := &OfferCommitmentVerification{OfferId: offer.OfferId, Hash: hash}
+
+	commitment, err := getOfferCommitment(ctx, offer.OfferId)
+	if err != nil {
+		return nil, err
+	}
+	if commitment == nil {
+		verification.Reason = "no commitment was published for this offer"
+		return verification, nil
+	}
+
+	verification.CompanyMSP = commitment.CompanyMSP
+	verification.IssuedAt = commitment.IssuedAt
+	verification.Matches = commitment.Hash == hash
+	if verification.Matches {
+		verification.Reason = fmt.Sprintf("offer matches the commitment published by %s", commitment.CompanyMSP)
+	} else {
+		verification.Reason = "offer terms differ from the published commitment"
+	}
+
+	return verification, nil
+}
+
+// ReadOfferCommitment retrieves the public commitment of an offer
+func (o *OfferContract) ReadOfferCommitment(ctx contractapi.TransactionContextInterface, offerId string) (*OfferCommitment, error) {
+	_, err := authorize(ctx, ActionOfferCommitment)
+	if err != nil {
+		return nil, err
+	}
+
+	commitment, err := getOfferCommitment(ctx, offerId)
+	if err != nil {
+		return nil, err
+	}
+	if commitment == nil {
+		return nil, newChaincodeError(CodeNotFound, "no commitment was published for offer %s", offerId)
+	}
+	return commitment, nil
+}
+
+// offerHash computes the hex SHA-256 of the offer's nonce followed by the canonical JSON of its
+// terms. Without the nonce the few likely values of the terms could be hashed until one matches.
+func offerHash(offer *Offer) (string, error) {
+	// Free-text CTCs of older offers are hashed as the plain string they were committed as
+	var ctc interface{} = offer.Ctc
+	if offer.Ctc != nil && offer.Ctc.Legacy != "" {
+		ctc = offer.Ctc.Legacy
+	}
+
+	canonicalBytes, err := json.Marshal(canonicalOffer{
+		OfferId:       offer.OfferId,
+		CompanyMSP:    offer.CompanyMSP,
+		CompanyName:   offer.CompanyName,
+		StudentId:     offer.StudentId,
+		ResultId:      offer.ResultId,
+		Name:          offer.Name,
+		Email:         offer.Email,
+		Ctc:           ctc,
+		DateOfJoining: offer.DateOfJoining,
+		DateOfRelease: offer.DateOfRelease,
+		IssuedAt:      offer.IssuedAt,
+		Revision:      offer.Revision,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal canonical offer: %v", err)
+	}
+
+	return saltedOfferHash(offer.Nonce, canonicalBytes), nil
+}
+
+// saltedOfferHash computes the hex SHA-256 of an offer nonce followed by the offer bytes
+func saltedOfferHash(nonce string, offerBytes []byte) string {
+	digest := sha256.Sum256(append([]byte(nonce), offerBytes...))
+	return hex.EncodeToString(digest[:])
+}
+
+// migratedOfferNonce derives the nonce of an offer issued before nonces were recorded from the
+// offerNonce transient field of the migration
+func migratedOfferNonce(ctx contractapi.TransactionContextInterface, offerId string) (string, error) {
+	transientData, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch transient data. %s", err)
+	}
+	secret, exists := transientData["offerNonce"]
+	if !exists || len(secret) < offerNonceLength {
+		return "", newChaincodeError(CodeInvalidArgument, "the offerNonce transient field must carry at least %d random bytes", offerNonceLength)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(offerId))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// putOfferCommitment publishes the commitment of an offer's current terms
+func putOfferCommitment(ctx contractapi.TransactionContextInterface, offer *Offer) error {
+	hash, err := offerHash(offer)
+	if err != nil {
+		return err
+	}
+
+	commitment := OfferCommitment{
+		AssetType:  "OfferCommitment",
+		OfferId:    offer.OfferId,
+		CompanyMSP: offer.CompanyMSP,
+		Hash:       hash,
+		IssuedAt:   offer.IssuedAt,
+		TxId:       ctx.GetStub().GetTxID(),
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(offerCommitmentObjectType, []string{offer.OfferId})
+	if err != nil {
+		return fmt.Errorf("could not create offer commitment key: %v", err)
+	}
+	commitmentBytes, err := json.Marshal(commitment)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer commitment: %v", err)
+	}
+	err = ctx.GetStub().PutState(key, commitmentBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store offer commitment in world state: %v", err)
+	}
+	return nil
+}
+
+// getOfferCommitment reads the commitment of an offer, returning nil if none was published
+func getOfferCommitment(ctx contractapi.TransactionContextInterface, offerId string) (*OfferCommitment, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(offerCommitmentObjectType, []string{offerId})
+	if err != nil {
+		return nil, fmt.Errorf("could not create offer commitment key: %v", err)
+	}
+
+	commitmentBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if commitmentBytes == nil {
+		return nil, nil
+	}
+
+	var commitment OfferCommitment
+	err = json.Unmarshal(commitmentBytes, &commitment)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal offer commitment: %v", err)
+	}
+
+	return &commitment, nil
+}
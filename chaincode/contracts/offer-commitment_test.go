@@ -0,0 +1,57 @@
+package contracts
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOfferHashIsSaltedWithTheNonce(t *testing.T) {
+	offer := &Offer{
+		OfferId:    "OFFER1",
+		CompanyMSP: "CompanyMSP",
+		StudentId:  "user1",
+		Ctc:        &Ctc{Fixed: 1000000, Currency: "INR", Period: PeriodAnnual},
+		Nonce:      strings.Repeat("ab", 32),
+	}
+	salted, err := offerHash(offer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer.Nonce = strings.Repeat("cd", 32)
+	resalted, err := offerHash(offer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if salted == resalted {
+		t.Fatal("offers with different nonces share a hash")
+	}
+}
+
+func TestCreateOfferRequiresANonce(t *testing.T) {
+	transientData := map[string][]byte{
+		"ctc":           []byte(`{"fixed":1000000,"variable":0,"currency":"INR","period":"annual"}`),
+		"dateOfJoining": []byte("2025-02-01"),
+		"dateOfRelease": []byte("2025-01-01"),
+		"name":          []byte("Stu One"),
+		"email":         []byte("stu@example.com"),
+		"companyName":   []byte("Company"),
+		"offerNonce":    []byte("short"),
+	}
+
+	var offer Offer
+	err := parseOfferTransient(transientData, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), &offer)
+	if err == nil || !strings.Contains(err.Error(), `"offerNonce"`) {
+		t.Fatalf("expected the short nonce to be rejected, got %v", err)
+	}
+
+	transientData["offerNonce"] = []byte("0123456789abcdef0123456789abcdef")
+	err = parseOfferTransient(transientData, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), &offer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offer.Nonce == "" {
+		t.Fatal("the nonce was not kept with the offer")
+	}
+}
@@ -0,0 +1,429 @@
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Offer status values
+const (
+	OfferIssued    string = "ISSUED"
+	OfferAccepted  string = "ACCEPTED"
+	OfferDeclined  string = "DECLINED"
+	OfferWithdrawn string = "WITHDRAWN"
+	OfferExpired   string = "EXPIRED"
+	OfferJoined    string = "JOINED"
+)
+
+// Object type of the composite key (offerId, txId) for offer history entries. The status change is
+// public; the full entry with actor and reason is kept in the offer's collection.
+const offerHistoryObjectType string = "offerHistory"
+
+// Response window applied when CreateOffer is not given a validUntil
+const defaultOfferValidity = 30 * 24 * time.Hour
+
+// offerTransitions lists, per status, the statuses an offer may move to
+var offerTransitions = map[string][]string{
+	OfferIssued:   {OfferAccepted, OfferDeclined, OfferWithdrawn, OfferExpired},
+	OfferAccepted: {OfferJoined, OfferWithdrawn},
+}
+
+// OfferHistoryEntry records one status change of an offer. Actor and reason are private and
+// missing once purged from the offer's collection.
+type OfferHistoryEntry struct {
+	AssetType string `json:"assetType"`                             // Asset type ("OfferHistoryEntry")
+	OfferId   string `json:"offerId"`                               // Offer that changed
+	From      string `json:"from"`                                  // Previous status, empty on issue
+	To        string `json:"to"`                                    // New status
+	Reason    string `json:"reason,omitempty" metadata:",optional"` // Note supplied with the change
+	Actor     string `json:"actor,omitempty" metadata:",optional"`  // Client identity that made the change
+	ActorMSP  string `json:"actorMsp"`                              // MSP of that identity
+	Timestamp string `json:"timestamp"`                             // Transaction timestamp (RFC3339)
+	TxId      string `json:"txId"`                                  // Transaction ID
+}
+
+// OfferSweep summarises an ExpireOffers run
+type OfferSweep struct {
+	Expired   []string `json:"expired"`   // Offers moved to EXPIRED
+	Purged    []string `json:"purged"`    // Offers whose terms were found purged and are now marked so on their record
+	Timestamp string   `json:"timestamp"` // Transaction timestamp (RFC3339)
+}
+
+// OfferEvent is the public chaincode event of a status change. It carries no offer terms.
+type OfferEvent struct {
+	OfferId   string `json:"offerId"`   // Offer that changed
+	From      string `json:"from"`      // Previous status
+	To        string `json:"to"`        // New status
+	Timestamp string `json:"timestamp"` // Transaction timestamp (RFC3339)
+	TxId      string `json:"txId"`      // Transaction ID
+}
+
+// AcceptOffer lets the student accept an issued offer before it expires
+func (o *OfferContract) AcceptOffer(ctx contractapi.TransactionContextInterface, offerId string) (string, error) {
+	offer, err := readAuthorizedOffer(ctx, ActionOfferRespond, offerId)
+	if err != nil {
+		return "", err
+	}
+	expired, err := offerPastDeadline(ctx, offer)
+	if err != nil {
+		return "", err
+	}
+	if expired {
+		return "", newChaincodeError(CodeConflict, "offer %s expired at %s and can no longer be accepted", offerId, offer.ValidUntil)
+	}
+
+	err = transitionOffer(ctx, offer, OfferAccepted, "")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Offer %v accepted", offerId), nil
+}
+
+// DeclineOffer lets the student decline an issued offer, with an optional reason
+func (o *OfferContract) DeclineOffer(ctx contractapi.TransactionContextInterface, offerId string, reason string) (string, error) {
+	offer, err := readAuthorizedOffer(ctx, ActionOfferRespond, offerId)
+	if err != nil {
+		return "", err
+	}
+
+	err = transitionOffer(ctx, offer, OfferDeclined, reason)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Offer %v declined", offerId), nil
+}
+
+// WithdrawOffer lets the issuing company withdraw an issued or accepted offer
+func (o *OfferContract) WithdrawOffer(ctx contractapi.TransactionContextInterface, offerId string, reason string) (string, error) {
+	offer, err := readAuthorizedOffer(ctx, ActionOfferManage, offerId)
+	if err != nil {
+		return "", err
+	}
+
+	err = transitionOffer(ctx, offer, OfferWithdrawn, reason)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Offer %v withdrawn", offerId), nil
+}
+
+// MarkJoined lets the issuing company record that the candidate joined on an accepted offer
+func (o *OfferContract) MarkJoined(ctx contractapi.TransactionContextInterface, offerId string) (string, error) {
+	offer, err := readAuthorizedOffer(ctx, ActionOfferManage, offerId)
+	if err != nil {
+		return "", err
+	}
+
+	err = transitionOffer(ctx, offer, OfferJoined, "")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Offer %v marked as joined", offerId), nil
+}
+
+// ExpireOffer moves an issued offer whose validUntil has passed to the expired state.
+// Either party may submit it.
+func (o *OfferContract) ExpireOffer(ctx contractapi.TransactionContextInterface, offerId string) (string, error) {
+	offer, err := readAuthorizedOffer(ctx, ActionOfferRead, offerId)
+	if err != nil {
+		return "", err
+	}
+	expired, err := offerPastDeadline(ctx, offer)
+	if err != nil {
+		return "", err
+	}
+	if !expired {
+		return "", newChaincodeError(CodeConflict, "offer %s is valid until %s and cannot expire yet", offerId, offer.ValidUntil)
+	}
+
+	err = transitionOffer(ctx, offer, OfferExpired, "")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Offer %v expired", offerId), nil
+}
+
+// ExpireOffers expires every issued offer of the caller whose validUntil has passed: all of the
+// company's offers for recruiters, the offers addressed to them for students. Offers of a recruiter's
+// company whose terms were purged are marked on their public record. A single "OffersSwept" event summarises the run,
+// as Fabric keeps only the last event of a transaction.
+func (o *OfferContract) ExpireOffers(ctx contractapi.TransactionContextInterface) (*OfferSweep, error) {
+	role, err := authorize(ctx, ActionOfferRead)
+	if err != nil {
+		return nil, err
+	}
+	records, err := readableOfferRecords(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	sweep := &OfferSweep{Expired: []string{}, Purged: []string{}, Timestamp: timestamp}
+	for _, record := range records {
+		if record.Purged {
+			continue
+		}
+
+		offer, err := getOffer(ctx, record.OfferId)
+		var purged *OfferPurgedError
+		if errors.As(err, &purged) {
+			// Purged terms no longer name their student, so only the issuing company is known to
+			// be a party and may mark them
+			if role != RoleHR || record.CompanyMSP != clientOrgID {
+				continue
+			}
+			err = markOfferPurged(ctx, record)
+			if err != nil {
+				return nil, err
+			}
+			sweep.Purged = append(sweep.Purged, record.OfferId)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if offer.Status != OfferIssued || authorizeOfferParty(ctx, role, offer) != nil {
+			continue
+		}
+		expired, err := offerPastDeadline(ctx, offer)
+		if err != nil {
+			return nil, err
+		}
+		if !expired {
+			continue
+		}
+		err = transitionOffer(ctx, offer, OfferExpired, "response deadline passed")
+		if err != nil {
+			return nil, err
+		}
+		sweep.Expired = append(sweep.Expired, offer.OfferId)
+	}
+
+	if len(sweep.Expired) > 0 || len(sweep.Purged) > 0 {
+		eventBytes, err := json.Marshal(sweep)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal offer sweep event: %v", err)
+		}
+		err = ctx.GetStub().SetEvent("OffersSwept", eventBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set offer sweep event: %v", err)
+		}
+	}
+	return sweep, nil
+}
+
+// GetOfferHistory lists the status changes of an offer, oldest first. Recruiters keep the public
+// status changes of offers whose terms were purged.
+func (o *OfferContract) GetOfferHistory(ctx contractapi.TransactionContextInterface, offerId string) ([]*OfferHistoryEntry, error) {
+	role, err := authorize(ctx, ActionOfferRead)
+	if err != nil {
+		return nil, err
+	}
+	// Recruiters are checked against the public record before the terms are read
+	_, err = readOfferAsParty(ctx, role, offerId)
+	var purged *OfferPurgedError
+	if err != nil && !(role == RoleHR && errors.As(err, &purged)) {
+		return nil, err
+	}
+	record, err := getOfferRecord(ctx, offerId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Full entries from the collection take precedence over their public status changes
+	private, err := getOfferHistoryEntries(ctx, offerId, func(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+		return ctx.GetStub().GetPrivateDataByPartialCompositeKey(record.Collection, objectType, keys)
+	})
+	if err != nil {
+		return nil, err
+	}
+	public, err := getOfferHistoryEntries(ctx, offerId, ctx.GetStub().GetStateByPartialCompositeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*OfferHistoryEntry, 0, len(public))
+	for _, entry := range public {
+		if full, ok := private[entry.TxId]; ok {
+			entry = full
+		}
+		delete(private, entry.TxId)
+		entries = append(entries, entry)
+	}
+	// Entries written before status changes were public only exist in the collection
+	for _, entry := range private {
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Timestamp != entries[j].Timestamp {
+			return entries[i].Timestamp < entries[j].Timestamp
+		}
+		return entries[i].TxId < entries[j].TxId
+	})
+	return entries, nil
+}
+
+// getOfferHistoryEntries reads the history entries of an offer through a partial composite key
+// query, keyed by transaction ID
+func getOfferHistoryEntries(ctx contractapi.TransactionContextInterface, offerId string, query func(string, []string) (shim.StateQueryIteratorInterface, error)) (map[string]*OfferHistoryEntry, error) {
+	historyIterator, err := query(offerHistoryObjectType, []string{offerId})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch offer history: %v", err)
+	}
+	defer historyIterator.Close()
+
+	entries := make(map[string]*OfferHistoryEntry)
+	for historyIterator.HasNext() {
+		queryResult, err := historyIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch offer history entry: %v", err)
+		}
+
+		var entry OfferHistoryEntry
+		err = json.Unmarshal(queryResult.Value, &entry)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal offer history entry: %v", err)
+		}
+		entries[entry.TxId] = &entry
+	}
+	return entries, nil
+}
+
+// transitionOffer moves an offer to a new status if the state machine allows it, stores it,
+// appends a history entry and emits the matching event
+func transitionOffer(ctx contractapi.TransactionContextInterface, offer *Offer, to string, reason string) error {
+	from := offer.Status
+	allowed := false
+	for _, next := range offerTransitions[from] {
+		if next == to {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return newChaincodeError(CodeConflict, "offer %s cannot move from %s to %s", offer.OfferId, from, to)
+	}
+
+	offer.Status = to
+	err := putOffer(ctx, offer)
+	if err != nil {
+		return err
+	}
+	return recordOfferHistory(ctx, offer, from, to, reason)
+}
+
+// recordOfferHistory writes a history entry next to the offer, publishes its status change and
+// emits an event such as "OfferAccepted"
+func recordOfferHistory(ctx contractapi.TransactionContextInterface, offer *Offer, from string, to string, reason string) error {
+	offerId := offer.OfferId
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	entry := OfferHistoryEntry{
+		AssetType: "OfferHistoryEntry",
+		OfferId:   offerId,
+		From:      from,
+		To:        to,
+		Reason:    reason,
+		Actor:     clientID,
+		ActorMSP:  clientOrgID,
+		Timestamp: timestamp,
+		TxId:      txID,
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(offerHistoryObjectType, []string{offerId, txID})
+	if err != nil {
+		return fmt.Errorf("could not create offer history key: %v", err)
+	}
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer history entry: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(offerCollectionName(offer.CompanyMSP), key, entryBytes)
+	if err != nil {
+		return fmt.Errorf("could not write offer history entry: %v", err)
+	}
+
+	// The public entry outlives the collection's purge and names no person
+	entry.Actor = ""
+	entry.Reason = ""
+	publicBytes, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer history entry: %v", err)
+	}
+	err = ctx.GetStub().PutState(key, publicBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store offer history entry in world state: %v", err)
+	}
+
+	eventBytes, err := json.Marshal(OfferEvent{OfferId: offerId, From: from, To: to, Timestamp: timestamp, TxId: txID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer event: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(offerEventName(to), eventBytes)
+	if err != nil {
+		return fmt.Errorf("failed to set offer event: %v", err)
+	}
+	return nil
+}
+
+// offerEventName maps a status to the name of the event announcing it
+func offerEventName(status string) string {
+	switch status {
+	case OfferIssued:
+		return "OfferIssued"
+	case OfferAccepted:
+		return "OfferAccepted"
+	case OfferDeclined:
+		return "OfferDeclined"
+	case OfferWithdrawn:
+		return "OfferWithdrawn"
+	case OfferExpired:
+		return "OfferExpired"
+	case OfferJoined:
+		return "OfferJoined"
+	}
+	return "OfferUpdated"
+}
+
+// offerPastDeadline reports whether the transaction time is after the offer's validUntil
+func offerPastDeadline(ctx contractapi.TransactionContextInterface, offer *Offer) (bool, error) {
+	if offer.ValidUntil == "" {
+		return false, nil
+	}
+	validUntil, err := time.Parse(time.RFC3339, offer.ValidUntil)
+	if err != nil {
+		return false, fmt.Errorf("offer %s has an invalid validUntil %q: %v", offer.OfferId, offer.ValidUntil, err)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	return now.After(validUntil), nil
+}
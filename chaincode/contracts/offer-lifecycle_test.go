@@ -0,0 +1,36 @@
+package contracts
+
+import "testing"
+
+func TestExpireOffersMarksPurgesOnlyForTheIssuingCompany(t *testing.T) {
+	stub := newRegistryStub(t)
+	student := newMockContext(stub, "StudentMSP", RoleStudent)
+	recruiter := newMockContext(stub, "CompanyMSP", RoleHR)
+
+	// The record outlives the terms, which are missing from the collection
+	err := putOfferRecord(recruiter, &OfferRecord{
+		AssetType:  "OfferRecord",
+		OfferId:    "OFFER1",
+		CompanyMSP: "CompanyMSP",
+		Collection: offerCollectionName("CompanyMSP"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sweep, err := new(OfferContract).ExpireOffers(student)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sweep.Purged) != 0 {
+		t.Fatalf("a student marked purged offers %v", sweep.Purged)
+	}
+
+	sweep, err = new(OfferContract).ExpireOffers(recruiter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sweep.Purged) != 1 || sweep.Purged[0] != "OFFER1" {
+		t.Fatalf("expected the company to mark OFFER1 purged, got %v", sweep.Purged)
+	}
+}
@@ -0,0 +1,282 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Object type of the composite key (offerId, resultId) for matches in the offer's collection
+const matchObjectType string = "match"
+
+// ResultMatch is one ranked candidate result for an offer
+type ResultMatch struct {
+	Rank       int            `json:"rank"`       // Position in the ranking, starting at 1
+	OfferId    string         `json:"offerId"`    // Offer matched against
+	ResultId   string         `json:"resultId"`   // Candidate result
+	StudentId  string         `json:"studentId"`  // Student holding the result
+	IssuerMSP  string         `json:"issuerMsp"`  // Institution that issued the result
+	Percentage float64        `json:"percentage"` // Percentage of the result
+	Eligible   bool           `json:"eligible"`   // Whether every rule of the offer's policy passed
+	Passed     []*RuleOutcome `json:"passed"`     // Rules the candidate satisfies
+	Failed     []*RuleOutcome `json:"failed"`     // Rules the candidate does not satisfy
+}
+
+// Match links an offer to the result and student it was matched with. It is kept in the offer's
+// collection, next to the offer terms.
+type Match struct {
+	AssetType     string  `json:"assetType"`     // Asset type ("Match")
+	OfferId       string  `json:"offerId"`       // Matched offer
+	ResultId      string  `json:"resultId"`      // Matched result
+	StudentId     string  `json:"studentId"`     // Student holding the result
+	PolicyId      string  `json:"policyId"`      // Policy the result satisfied
+	PolicyVersion int     `json:"policyVersion"` // Version of that policy
+	Percentage    float64 `json:"percentage"`    // Percentage of the result at match time
+	MatchedBy     string  `json:"matchedBy"`     // Recruiter identity that recorded the match
+	MatchedAt     string  `json:"matchedAt"`     // Transaction timestamp (RFC3339)
+	TxId          string  `json:"txId"`          // Transaction ID
+}
+
+// SetOfferPolicy attaches one of the company's eligibility policies to an offer, defining the
+// requirements candidates are matched against
+func (o *OfferContract) SetOfferPolicy(ctx contractapi.TransactionContextInterface, offerId string, policyId string) (string, error) {
+	offer, err := readAuthorizedOffer(ctx, ActionOfferManage, offerId)
+	if err != nil {
+		return "", err
+	}
+	_, err = getOwnedEligibilityPolicy(ctx, policyId)
+	if err != nil {
+		return "", err
+	}
+
+	offer.PolicyId = policyId
+	err = putOffer(ctx, offer)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Offer %v now matches candidates against policy %v", offerId, policyId), nil
+}
+
+// GetMatchingResults ranks the results the company may read under consent against the policy of an
+// offer: eligible candidates first, then by fewest failed rules and highest percentage. Every
+// student with an active grant to the recruiter is a candidate, whoever the offer is addressed to;
+// transcript rules fail as not readable for students who did not grant TranscriptConsent.
+func (o *OfferContract) GetMatchingResults(ctx contractapi.TransactionContextInterface, offerId string) ([]*ResultMatch, error) {
+	offer, policy, err := readMatchableOffer(ctx, offerId)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := getConsentedResults(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	readable, err := readableResultsWithMarks(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	// Transcript rules are applied once per student, under that student's transcript consent
+	transcriptOutcomes := make(map[string][]*RuleOutcome)
+	matches := []*ResultMatch{}
+	for _, result := range readable {
+		if _, loaded := transcriptOutcomes[result.StudentId]; !loaded {
+			transcriptOutcomes[result.StudentId], err = applyReadableTranscriptRules(ctx, policy.Rules, result.StudentId)
+			if err != nil {
+				return nil, err
+			}
+		}
+		match, err := matchResult(ctx, offer, policy, result, transcriptOutcomes[result.StudentId])
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Eligible != b.Eligible {
+			return a.Eligible
+		}
+		if len(a.Failed) != len(b.Failed) {
+			return len(a.Failed) < len(b.Failed)
+		}
+		if a.Percentage != b.Percentage {
+			return a.Percentage > b.Percentage
+		}
+		return a.ResultId < b.ResultId
+	})
+	for i, match := range matches {
+		match.Rank = i + 1
+	}
+	return matches, nil
+}
+
+// MatchResult records that a result satisfying every rule of the offer's policy was matched with
+// the offer. The recruiter must be able to read the result under consent.
+func (o *OfferContract) MatchResult(ctx contractapi.TransactionContextInterface, offerId string, resultId string) (*Match, error) {
+	offer, policy, err := readMatchableOffer(ctx, offerId)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := getResult(ctx, resultId)
+	if err != nil {
+		return nil, err
+	}
+	err = authorizeResultRead(ctx, result)
+	if err != nil {
+		return nil, err
+	}
+	err = loadResultMarks(ctx, result)
+	if err != nil {
+		return nil, err
+	}
+
+	transcriptOutcomes, err := applyReadableTranscriptRules(ctx, policy.Rules, result.StudentId)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := matchResult(ctx, offer, policy, result, transcriptOutcomes)
+	if err != nil {
+		return nil, err
+	}
+	if !candidate.Eligible {
+		failed := make([]string, 0, len(candidate.Failed))
+		for _, outcome := range candidate.Failed {
+			failed = append(failed, outcome.Rule)
+		}
+		return nil, newChaincodeError(CodeInvalidArgument, "result %s does not satisfy policy %s: failed %v", resultId, policy.PolicyId, failed)
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	matchedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	match := &Match{
+		AssetType:     "Match",
+		OfferId:       offerId,
+		ResultId:      resultId,
+		StudentId:     result.StudentId,
+		PolicyId:      policy.PolicyId,
+		PolicyVersion: policy.Version,
+		Percentage:    result.Percentage,
+		MatchedBy:     clientID,
+		MatchedAt:     matchedAt,
+		TxId:          ctx.GetStub().GetTxID(),
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(matchObjectType, []string{offerId, resultId})
+	if err != nil {
+		return nil, fmt.Errorf("could not create match key: %v", err)
+	}
+	matchBytes, err := json.Marshal(match)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal match: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(offerCollectionName(offer.CompanyMSP), key, matchBytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not write match: %v", err)
+	}
+	return match, nil
+}
+
+// GetOfferMatches lists the matches recorded for an offer, to its company and named student
+func (o *OfferContract) GetOfferMatches(ctx contractapi.TransactionContextInterface, offerId string) ([]*Match, error) {
+	offer, err := readAuthorizedOffer(ctx, ActionOfferRead, offerId)
+	if err != nil {
+		return nil, err
+	}
+
+	matchesIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(offerCollectionName(offer.CompanyMSP), matchObjectType, []string{offerId})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch matches: %v", err)
+	}
+	defer matchesIterator.Close()
+
+	var matches []*Match
+	for matchesIterator.HasNext() {
+		queryResult, err := matchesIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch match: %v", err)
+		}
+
+		var match Match
+		err = json.Unmarshal(queryResult.Value, &match)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal match: %v", err)
+		}
+		matches = append(matches, &match)
+	}
+
+	return matches, nil
+}
+
+// readMatchableOffer loads the public record of an offer of the calling company together with its
+// eligibility policy. Matching reads result marks, so it is endorsed by peers of the marks
+// collection, which do not hold the offer terms.
+func readMatchableOffer(ctx contractapi.TransactionContextInterface, offerId string) (*OfferRecord, *EligibilityPolicy, error) {
+	_, err := authorize(ctx, ActionOfferManage)
+	if err != nil {
+		return nil, nil, err
+	}
+	record, err := getOfferRecord(ctx, offerId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if record == nil {
+		return nil, nil, newChaincodeError(CodeNotFound, "the offer %s does not exist", offerId)
+	}
+	err = authorizeOfferParty(ctx, RoleHR, &Offer{OfferId: offerId, CompanyMSP: record.CompanyMSP})
+	if err != nil {
+		return nil, nil, err
+	}
+	if record.Purged {
+		return nil, nil, &OfferPurgedError{Code: "OFFER_PURGED", OfferId: offerId, Collection: record.Collection, Hash: record.Hash}
+	}
+	if record.PolicyId == "" {
+		return nil, nil, newChaincodeError(CodeConflict, "offer %s has no eligibility policy; attach one with SetOfferPolicy", offerId)
+	}
+	policy, err := getOwnedEligibilityPolicy(ctx, record.PolicyId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, policy, nil
+}
+
+// matchResult applies the result rules of a policy to one candidate result and combines them with
+// the outcomes of its transcript rules for the student
+func matchResult(ctx contractapi.TransactionContextInterface, offer *OfferRecord, policy *EligibilityPolicy, result *Result, transcriptOutcomes []*RuleOutcome) (*ResultMatch, error) {
+	outcomes, err := applyResultRules(ctx, policy.Rules, result)
+	if err != nil {
+		return nil, err
+	}
+	outcomes = append(outcomes, transcriptOutcomes...)
+
+	match := &ResultMatch{
+		OfferId:    offer.OfferId,
+		ResultId:   result.ResultId,
+		StudentId:  result.StudentId,
+		IssuerMSP:  result.IssuerMSP,
+		Percentage: result.Percentage,
+		Passed:     []*RuleOutcome{},
+		Failed:     []*RuleOutcome{},
+	}
+	for _, outcome := range outcomes {
+		if outcome.Passed {
+			match.Passed = append(match.Passed, outcome)
+		} else {
+			match.Failed = append(match.Failed, outcome)
+		}
+	}
+	match.Eligible = len(match.Failed) == 0
+	return match, nil
+}
@@ -0,0 +1,66 @@
+package contracts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchResultRequiresTranscriptConsent(t *testing.T) {
+	stub := newRegistryStub(t)
+	registrar := newMockContext(stub, "UniversityMSP", RoleRegistrar)
+	student := newMockContext(stub, "StudentMSP", RoleStudent)
+	recruiter := newMockContext(stub, "CompanyMSP", RoleHR)
+
+	_, err := new(ResultContract).CreateResult(registrar, "RES1", "user1", 100, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = new(ResultContract).AddCourseGrade(registrar, "user1", "2024-SEM2", "CS101", "Programming Fundamentals", 4, 9, "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = new(ConsentContract).GrantConsent(student, "G1", "CompanyMSP", mockClientID, []string{"RES1"}, "2026-12-31T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	offers := new(OfferContract)
+	_, err = offers.CreateEligibilityPolicy(recruiter, "P1", "CS graduates", `{"requiredCourses":["CS101"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = putOfferRecord(recruiter, &OfferRecord{
+		AssetType:  "OfferRecord",
+		OfferId:    "OFFER1",
+		CompanyMSP: "CompanyMSP",
+		Collection: offerCollectionName("CompanyMSP"),
+		PolicyId:   "P1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The result is readable, but the course grades are not
+	matches, err := offers.GetMatchingResults(recruiter, "OFFER1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Eligible || !strings.Contains(matches[0].Failed[0].Detail, "not readable") {
+		t.Fatalf("expected the required course rule to fail as not readable, got %+v", matches)
+	}
+	_, err = offers.MatchResult(recruiter, "OFFER1", "RES1")
+	if err == nil {
+		t.Fatal("expected a match relying on unreadable grades to be rejected")
+	}
+
+	_, err = new(ConsentContract).GrantConsent(student, "G2", "CompanyMSP", mockClientID, []string{TranscriptConsent}, "2026-12-31T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	match, err := offers.MatchResult(recruiter, "OFFER1", "RES1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match.StudentId != "user1" {
+		t.Fatalf("unexpected match %+v", match)
+	}
+}
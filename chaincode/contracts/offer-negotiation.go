@@ -0,0 +1,318 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Revision status values
+const (
+	RevisionProposed   string = "PROPOSED"
+	RevisionAccepted   string = "ACCEPTED"
+	RevisionSuperseded string = "SUPERSEDED"
+)
+
+// Object type of the composite key (offerId, version) for revisions in the offer's collection
+const offerRevisionObjectType string = "offerRevision"
+
+// OfferRevision is one round of negotiation on an offer: new terms proposed by the company or
+// the student, kept next to the offer in its private collection
+type OfferRevision struct {
+	AssetType     string `json:"assetType"`                                    // Asset type ("OfferRevision")
+	OfferId       string `json:"offerId"`                                      // Offer under negotiation
+	Version       int    `json:"version"`                                      // Revision number, starting at 1
+	Ctc           *Ctc   `json:"ctc,omitempty" metadata:",optional"`           // Proposed CTC, unset if unchanged
+	DateOfJoining string `json:"dateOfJoining,omitempty" metadata:",optional"` // Proposed joining date, unset if unchanged
+	Note          string `json:"note,omitempty" metadata:",optional"`          // Message accompanying the proposal
+	Status        string `json:"status"`                                       // PROPOSED, ACCEPTED or SUPERSEDED
+	ProposedBy    string `json:"proposedBy"`                                   // Client identity that proposed the revision
+	ProposerMSP   string `json:"proposerMsp"`                                  // MSP of that identity
+	ProposerRole  string `json:"proposerRole"`                                 // "hr" or "student"
+	ProposedAt    string `json:"proposedAt"`                                   // Transaction timestamp of the proposal (RFC3339)
+	AcceptedBy    string `json:"acceptedBy,omitempty" metadata:",optional"`    // Client identity that accepted the revision
+	AcceptedAt    string `json:"acceptedAt,omitempty" metadata:",optional"`    // Transaction timestamp of the acceptance (RFC3339)
+	TxId          string `json:"txId"`                                         // Transaction that proposed the revision
+}
+
+// OfferRevisionEvent is the public chaincode event of a negotiation step. It carries no terms.
+type OfferRevisionEvent struct {
+	OfferId   string `json:"offerId"`   // Offer under negotiation
+	Version   int    `json:"version"`   // Revision concerned
+	Status    string `json:"status"`    // New status of the revision
+	Timestamp string `json:"timestamp"` // Transaction timestamp (RFC3339)
+	TxId      string `json:"txId"`      // Transaction ID
+}
+
+// ProposeRevision lets the company or the named student propose new terms for an issued offer.
+// The terms are read from the transient "ctc" and "dateOfJoining" keys; earlier pending
+// proposals are superseded.
+func (o *OfferContract) ProposeRevision(ctx contractapi.TransactionContextInterface, offerId string, note string) (string, error) {
+	role, offer, err := readNegotiableOffer(ctx, offerId)
+	if err != nil {
+		return "", err
+	}
+
+	transientData, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch transient data. %s", err)
+	}
+	var revision OfferRevision
+	err = parseRevisionTransient(transientData, offer, &revision)
+	if err != nil {
+		return "", err
+	}
+
+	revisions, err := getOfferRevisions(ctx, offer)
+	if err != nil {
+		return "", err
+	}
+	// Revisions are numbered from the public record, as purged revisions leave the collection
+	record, err := getOfferRecord(ctx, offerId)
+	if err != nil {
+		return "", err
+	}
+	version := record.Revisions
+	for _, previous := range revisions {
+		if previous.Version > version {
+			version = previous.Version
+		}
+		if previous.Status == RevisionProposed {
+			previous.Status = RevisionSuperseded
+			err = putOfferRevision(ctx, offer, previous)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	proposedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	revision.ProposedBy, err = ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	revision.ProposerMSP, err = ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	revision.AssetType = "OfferRevision"
+	revision.OfferId = offerId
+	revision.Version = version + 1
+	revision.Note = note
+	revision.Status = RevisionProposed
+	revision.ProposerRole = role
+	revision.ProposedAt = proposedAt
+	revision.TxId = ctx.GetStub().GetTxID()
+
+	err = putOfferRevision(ctx, offer, &revision)
+	if err != nil {
+		return "", err
+	}
+	record.Revisions = revision.Version
+	err = putOfferRecord(ctx, record)
+	if err != nil {
+		return "", err
+	}
+	err = emitRevisionEvent(ctx, "OfferRevisionProposed", &revision)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Revision %d proposed for offer %v", revision.Version, offerId), nil
+}
+
+// AcceptRevision lets the other party accept a pending revision, making its terms the binding
+// offer. The offer's public commitment is republished for the revised terms.
+func (o *OfferContract) AcceptRevision(ctx contractapi.TransactionContextInterface, offerId string, version int) (string, error) {
+	role, offer, err := readNegotiableOffer(ctx, offerId)
+	if err != nil {
+		return "", err
+	}
+
+	revision, err := getOfferRevision(ctx, offer, version)
+	if err != nil {
+		return "", err
+	}
+	if revision.Status != RevisionProposed {
+		return "", newChaincodeError(CodeConflict, "revision %d of offer %s is %s and cannot be accepted", version, offerId, revision.Status)
+	}
+	if revision.ProposerRole == role {
+		return "", newChaincodeError(CodeForbidden, "revision %d of offer %s was proposed by the %s side and must be accepted by the other party", version, offerId, role)
+	}
+
+	acceptedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	revision.AcceptedBy, err = ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	revision.AcceptedAt = acceptedAt
+	revision.Status = RevisionAccepted
+
+	// Apply the revised terms and publish their commitment
+	if revision.Ctc != nil {
+		offer.Ctc = revision.Ctc
+	}
+	if revision.DateOfJoining != "" {
+		offer.DateOfJoining = revision.DateOfJoining
+	}
+	offer.Revision = version
+
+	err = putOffer(ctx, offer)
+	if err != nil {
+		return "", err
+	}
+	err = putOfferCommitment(ctx, offer)
+	if err != nil {
+		return "", err
+	}
+	err = putOfferRevision(ctx, offer, revision)
+	if err != nil {
+		return "", err
+	}
+	err = emitRevisionEvent(ctx, "OfferRevisionAccepted", revision)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Revision %d of offer %v accepted", version, offerId), nil
+}
+
+// GetNegotiationThread lists the revisions of an offer, oldest first, to its company and student
+func (o *OfferContract) GetNegotiationThread(ctx contractapi.TransactionContextInterface, offerId string) ([]*OfferRevision, error) {
+	offer, err := readAuthorizedOffer(ctx, ActionOfferRead, offerId)
+	if err != nil {
+		return nil, err
+	}
+	return getOfferRevisions(ctx, offer)
+}
+
+// readNegotiableOffer loads an offer the caller is a party to and checks it is still open to negotiation
+func readNegotiableOffer(ctx contractapi.TransactionContextInterface, offerId string) (string, *Offer, error) {
+	role, err := authorize(ctx, ActionOfferNegotiate)
+	if err != nil {
+		return "", nil, err
+	}
+	offer, err := readOfferAsParty(ctx, role, offerId)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if offer.Status != OfferIssued {
+		return "", nil, newChaincodeError(CodeConflict, "offer %s is %s and can no longer be negotiated", offerId, offer.Status)
+	}
+	expired, err := offerPastDeadline(ctx, offer)
+	if err != nil {
+		return "", nil, err
+	}
+	if expired {
+		return "", nil, newChaincodeError(CodeConflict, "offer %s expired at %s and can no longer be negotiated", offerId, offer.ValidUntil)
+	}
+	return role, offer, nil
+}
+
+// getOfferRevisions reads the revisions of an offer from its collection, ordered by version
+func getOfferRevisions(ctx contractapi.TransactionContextInterface, offer *Offer) ([]*OfferRevision, error) {
+	revisionsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(offerCollectionName(offer.CompanyMSP), offerRevisionObjectType, []string{offer.OfferId})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch offer revisions: %v", err)
+	}
+	defer revisionsIterator.Close()
+
+	var revisions []*OfferRevision
+	for revisionsIterator.HasNext() {
+		queryResult, err := revisionsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch offer revision: %v", err)
+		}
+
+		var revision OfferRevision
+		err = json.Unmarshal(queryResult.Value, &revision)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal offer revision: %v", err)
+		}
+		revisions = append(revisions, &revision)
+	}
+
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return revisions[i].Version < revisions[j].Version
+	})
+	return revisions, nil
+}
+
+// getOfferRevision reads one revision of an offer
+func getOfferRevision(ctx contractapi.TransactionContextInterface, offer *Offer, version int) (*OfferRevision, error) {
+	key, err := offerRevisionKey(ctx, offer.OfferId, version)
+	if err != nil {
+		return nil, err
+	}
+
+	revisionBytes, err := ctx.GetStub().GetPrivateData(offerCollectionName(offer.CompanyMSP), key)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the private data. %s", err)
+	}
+	if revisionBytes == nil {
+		return nil, newChaincodeError(CodeNotFound, "offer %s has no revision %d", offer.OfferId, version)
+	}
+
+	var revision OfferRevision
+	err = json.Unmarshal(revisionBytes, &revision)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal offer revision: %v", err)
+	}
+	return &revision, nil
+}
+
+// putOfferRevision writes a revision to the collection of its offer
+func putOfferRevision(ctx contractapi.TransactionContextInterface, offer *Offer, revision *OfferRevision) error {
+	key, err := offerRevisionKey(ctx, revision.OfferId, revision.Version)
+	if err != nil {
+		return err
+	}
+	revisionBytes, err := json.Marshal(revision)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer revision: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(offerCollectionName(offer.CompanyMSP), key, revisionBytes)
+	if err != nil {
+		return fmt.Errorf("could not write offer revision: %v", err)
+	}
+	return nil
+}
+
+// offerRevisionKey builds the composite key of a revision
+func offerRevisionKey(ctx contractapi.TransactionContextInterface, offerId string, version int) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(offerRevisionObjectType, []string{offerId, fmt.Sprintf("%06d", version)})
+	if err != nil {
+		return "", fmt.Errorf("could not create offer revision key: %v", err)
+	}
+	return key, nil
+}
+
+// emitRevisionEvent announces a negotiation step without revealing the proposed terms
+func emitRevisionEvent(ctx contractapi.TransactionContextInterface, name string, revision *OfferRevision) error {
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	eventBytes, err := json.Marshal(OfferRevisionEvent{
+		OfferId:   revision.OfferId,
+		Version:   revision.Version,
+		Status:    revision.Status,
+		Timestamp: timestamp,
+		TxId:      ctx.GetStub().GetTxID(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer revision event: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, eventBytes)
+	if err != nil {
+		return fmt.Errorf("failed to set offer revision event: %v", err)
+	}
+	return nil
+}
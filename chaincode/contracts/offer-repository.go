@@ -0,0 +1,356 @@
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Offer terms are kept in a collection per company, named offersCollectionPrefix + MSP ID and
+// shared only with StudentMSP, so that no company can read a rival's offers. The public ledger
+// holds an OfferRecord per offer pointing at the collection, with the hash of the stored terms.
+// The collections purge their data after blockToLive blocks; the record then remains as the only
+// trace of the offer and is marked as purged by ExpireOffers.
+
+// Prefix of the per-company offer collections, e.g. "offers_CompanyMSP"
+const offersCollectionPrefix string = "offers_"
+
+// Object type of the composite key (offerId) for public offer records
+const offerObjectType string = "offer"
+
+// OfferRecord is the public trace of an offer: where its terms live and their hash
+type OfferRecord struct {
+	AssetType  string `json:"assetType"`                                // Asset type ("OfferRecord")
+	OfferId    string `json:"offerId"`                                  // Offer identifier
+	CompanyMSP string `json:"companyMsp"`                               // MSP of the issuing company
+	Collection string `json:"collection"`                               // Private data collection holding the offer terms
+	PolicyId   string `json:"policyId,omitempty" metadata:",optional"`  // Eligibility policy candidates are matched against
+	Hash       string `json:"hash"`                                     // Hex SHA-256 of the offer nonce followed by the stored terms
+	UpdatedAt  string `json:"updatedAt"`                                // Transaction timestamp of the last write (RFC3339)
+	Purged     bool   `json:"purged,omitempty" metadata:",optional"`    // Whether the terms were found purged from the collection
+	PurgedAt   string `json:"purgedAt,omitempty" metadata:",optional"`  // Transaction timestamp at which the purge was recorded (RFC3339)
+	Revisions  int    `json:"revisions,omitempty" metadata:",optional"` // Number of the latest revision proposed, kept as revisions may be purged
+}
+
+// OfferPurgedError reports an offer whose record is on the ledger but whose terms were purged
+// from its collection. Like OfferValidationError, its message is JSON.
+type OfferPurgedError struct {
+	Code       string `json:"code"`       // Always "OFFER_PURGED"
+	OfferId    string `json:"offerId"`    // Purged offer
+	Collection string `json:"collection"` // Collection the terms were purged from
+	Hash       string `json:"hash"`       // Hash of the last stored terms, which is all that remains
+}
+
+func (e *OfferPurgedError) Error() string {
+	errorBytes, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf("the terms of offer %s were purged; only their hash %s remains", e.OfferId, e.Hash)
+	}
+	return string(errorBytes)
+}
+
+// offerCollectionName returns the private data collection of a company's offers. Offers are
+// isolated per MSP only: companies sharing an MSP share its collection and can act on each
+// other's offers.
+func offerCollectionName(companyMSP string) string {
+	return offersCollectionPrefix + companyMSP
+}
+
+// getOfferRecord reads the public record of an offer, returning nil if it does not exist
+func getOfferRecord(ctx contractapi.TransactionContextInterface, offerId string) (*OfferRecord, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(offerObjectType, []string{offerId})
+	if err != nil {
+		return nil, fmt.Errorf("could not create offer key: %v", err)
+	}
+
+	recordBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if recordBytes == nil {
+		return nil, nil
+	}
+
+	var record OfferRecord
+	err = json.Unmarshal(recordBytes, &record)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal offer record: %v", err)
+	}
+
+	return &record, nil
+}
+
+// getOfferRecords lists the public offer records, restricted to one company unless companyMSP is empty
+func getOfferRecords(ctx contractapi.TransactionContextInterface, companyMSP string) ([]*OfferRecord, error) {
+	recordsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(offerObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch offer records: %v", err)
+	}
+	defer recordsIterator.Close()
+
+	var records []*OfferRecord
+	for recordsIterator.HasNext() {
+		queryResult, err := recordsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch offer record: %v", err)
+		}
+
+		var record OfferRecord
+		err = json.Unmarshal(queryResult.Value, &record)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal offer record: %v", err)
+		}
+		if companyMSP == "" || record.CompanyMSP == companyMSP {
+			records = append(records, &record)
+		}
+	}
+
+	return records, nil
+}
+
+// getOffer reads an offer from its company's collection without applying authorization.
+// Offers written before the lifecycle existed are reported as issued.
+func getOffer(ctx contractapi.TransactionContextInterface, offerId string) (*Offer, error) {
+	record, err := getOfferRecord(ctx, offerId)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, newChaincodeError(CodeNotFound, "the offer %s does not exist", offerId)
+	}
+
+	offerBytes, err := ctx.GetStub().GetPrivateData(record.Collection, offerId)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the private data. %s", err)
+	}
+	// Non-member peers fail the read above, so missing data under an existing record was purged
+	if offerBytes == nil {
+		return nil, &OfferPurgedError{Code: "OFFER_PURGED", OfferId: offerId, Collection: record.Collection, Hash: record.Hash}
+	}
+
+	var offer Offer
+	err = json.Unmarshal(offerBytes, &offer)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal private data collection data to type Offer")
+	}
+	if offer.Status == "" {
+		offer.Status = OfferIssued
+	}
+
+	return &offer, nil
+}
+
+// getOfferOrPurged reads an offer like getOffer, but stands in a placeholder carrying a warning
+// for an offer whose terms were purged
+func getOfferOrPurged(ctx contractapi.TransactionContextInterface, record *OfferRecord) (*Offer, error) {
+	offer, err := getOffer(ctx, record.OfferId)
+	var purged *OfferPurgedError
+	if errors.As(err, &purged) {
+		return &Offer{
+			OfferId:    record.OfferId,
+			AssetType:  "OfferLetter",
+			CompanyMSP: record.CompanyMSP,
+			Purged:     true,
+			Warning:    fmt.Sprintf("the terms were purged from %s; only the hash %s remains", record.Collection, record.Hash),
+		}, nil
+	}
+	return offer, err
+}
+
+// markOfferPurged records on the public ledger that an offer's terms were purged
+func markOfferPurged(ctx contractapi.TransactionContextInterface, record *OfferRecord) error {
+	purgedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	record.Purged = true
+	record.PurgedAt = purgedAt
+	return putOfferRecord(ctx, record)
+}
+
+// putOfferRecord writes the public record of an offer
+func putOfferRecord(ctx contractapi.TransactionContextInterface, record *OfferRecord) error {
+	key, err := ctx.GetStub().CreateCompositeKey(offerObjectType, []string{record.OfferId})
+	if err != nil {
+		return fmt.Errorf("could not create offer key: %v", err)
+	}
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer record: %v", err)
+	}
+	err = ctx.GetStub().PutState(key, recordBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store offer record in world state: %v", err)
+	}
+	return nil
+}
+
+// putOffer writes an offer to its company's collection and refreshes the public record
+func putOffer(ctx contractapi.TransactionContextInterface, offer *Offer) error {
+	if offer.CompanyMSP == "" {
+		return fmt.Errorf("offer %s has no issuing company", offer.OfferId)
+	}
+	collection := offerCollectionName(offer.CompanyMSP)
+
+	offerBytes, err := json.Marshal(offer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(collection, offer.OfferId, offerBytes)
+	if err != nil {
+		return fmt.Errorf("could not able to write the data")
+	}
+
+	updatedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	record := OfferRecord{
+		AssetType:  "OfferRecord",
+		OfferId:    offer.OfferId,
+		CompanyMSP: offer.CompanyMSP,
+		Collection: collection,
+		PolicyId:   offer.PolicyId,
+		Hash:       saltedOfferHash(offer.Nonce, offerBytes),
+		UpdatedAt:  updatedAt,
+	}
+
+	// Counters on the record outlive the terms and are carried over
+	existing, err := getOfferRecord(ctx, offer.OfferId)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		record.Revisions = existing.Revisions
+	}
+	return putOfferRecord(ctx, &record)
+}
+
+// deleteOffer removes an offer's terms and its public record
+func deleteOffer(ctx contractapi.TransactionContextInterface, record *OfferRecord) error {
+	err := ctx.GetStub().DelPrivateData(record.Collection, record.OfferId)
+	if err != nil {
+		return fmt.Errorf("could not delete the private data. %s", err)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(offerObjectType, []string{record.OfferId})
+	if err != nil {
+		return fmt.Errorf("could not create offer key: %v", err)
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// readableOfferRecords lists the offer records the caller may read: their own company's for
+// recruiters, every company's for students
+func readableOfferRecords(ctx contractapi.TransactionContextInterface, role string) ([]*OfferRecord, error) {
+	if role != RoleHR {
+		return getOfferRecords(ctx, "")
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	return getOfferRecords(ctx, clientOrgID)
+}
+
+// readAuthorizedOffer loads an offer the caller may act on under action: recruiters only their
+// own company's offers, students only offers that name them
+func readAuthorizedOffer(ctx contractapi.TransactionContextInterface, action string, offerId string) (*Offer, error) {
+	role, err := authorize(ctx, action)
+	if err != nil {
+		return nil, err
+	}
+	return readOfferAsParty(ctx, role, offerId)
+}
+
+// readOfferAsParty loads an offer for a caller already authorized under role, checking that
+// they are a party to it
+func readOfferAsParty(ctx contractapi.TransactionContextInterface, role string, offerId string) (*Offer, error) {
+	// Check the company on the public record first, as rivals cannot read the collection
+	if role == RoleHR {
+		record, err := getOfferRecord(ctx, offerId)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			return nil, newChaincodeError(CodeNotFound, "the offer %s does not exist", offerId)
+		}
+		err = authorizeOfferParty(ctx, role, &Offer{OfferId: offerId, CompanyMSP: record.CompanyMSP})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	offer, err := getOffer(ctx, offerId)
+	if err != nil {
+		return nil, err
+	}
+	err = authorizeOfferParty(ctx, role, offer)
+	if err != nil {
+		return nil, err
+	}
+	return offer, nil
+}
+
+// authorizeOfferParty checks that the caller is the issuing company or the named student of an
+// offer. The issuing company is identified by its MSP, which every recruiter of the MSP shares.
+func authorizeOfferParty(ctx contractapi.TransactionContextInterface, role string, offer *Offer) error {
+	switch role {
+	case RoleHR:
+		clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+		if err != nil {
+			return fmt.Errorf("could not fetch client identity: %s", err)
+		}
+		if offer.CompanyMSP != clientOrgID {
+			return newChaincodeError(CodeForbidden, "offer %s belongs to %s and cannot be accessed by %v", offer.OfferId, offer.CompanyMSP, clientOrgID)
+		}
+	case RoleStudent:
+		studentId, err := clientStudentId(ctx)
+		if err != nil {
+			return err
+		}
+		if offer.StudentId == "" || offer.StudentId != studentId {
+			return newChaincodeError(CodeForbidden, "offer %s is not addressed to student %s", offer.OfferId, studentId)
+		}
+	}
+	return nil
+}
+
+// queryOffers runs a CouchDB selector over the offer collections the caller may read and keeps
+// the offers the caller is a party to
+func queryOffers(ctx contractapi.TransactionContextInterface, role string, queryString string) ([]*Offer, error) {
+	records, err := readableOfferRecords(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	queried := make(map[string]bool)
+	var offers []*Offer
+	for _, record := range records {
+		if queried[record.Collection] {
+			continue
+		}
+		queried[record.Collection] = true
+
+		resultsIterator, err := ctx.GetStub().GetPrivateDataQueryResult(record.Collection, queryString)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch the query result. %s", err)
+		}
+		collectionOffers, err := OfferResultIteratorFunction(resultsIterator)
+		resultsIterator.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, offer := range collectionOffers {
+			if authorizeOfferParty(ctx, role, offer) == nil {
+				offers = append(offers, offer)
+			}
+		}
+	}
+
+	return offers, nil
+}
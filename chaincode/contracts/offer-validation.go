@@ -0,0 +1,263 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Compensation periods of a CTC
+const (
+	PeriodAnnual  string = "annual"
+	PeriodMonthly string = "monthly"
+)
+
+// Transient keys accepted by CreateOffer and whether each is required
+var offerTransientFields = map[string]bool{
+	"ctc":           true,
+	"dateOfJoining": true,
+	"dateOfRelease": true,
+	"name":          true,
+	"email":         true,
+	"companyName":   true,
+	"validUntil":    false,
+	"offerNonce":    true,
+}
+
+// Minimum number of random bytes in the offerNonce transient field
+const offerNonceLength int = 32
+
+// ISO 4217 currency codes are three upper case letters
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Ctc is the structured cost to company of an offer
+type Ctc struct {
+	Fixed    float64 `json:"fixed"`                                 // Fixed component
+	Variable float64 `json:"variable"`                              // Variable component
+	Currency string  `json:"currency"`                              // ISO 4217 currency code, e.g. "INR"
+	Period   string  `json:"period"`                                // "annual" or "monthly"
+	Legacy   string  `json:"legacy,omitempty" metadata:",optional"` // Free-text CTC of offers created before validation
+}
+
+// UnmarshalJSON accepts the free-text CTC strings of offers created before validation. A decoder's
+// DisallowUnknownFields does not reach custom unmarshalers, so objects are decoded strictly here.
+func (c *Ctc) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*c = Ctc{}
+		return json.Unmarshal(data, &c.Legacy)
+	}
+
+	type ctcFields Ctc
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*ctcFields)(c))
+}
+
+// FieldError describes why one field of an offer was rejected
+type FieldError struct {
+	Field   string `json:"field"`   // Transient key, or key path inside the ctc such as "ctc.currency"
+	Message string `json:"message"` // What is wrong with the value
+}
+
+// OfferValidationError lists every rejected field. Its message is JSON so that clients can
+// parse it out of the endorsement error.
+type OfferValidationError struct {
+	Code   string        `json:"code"`   // "INVALID_OFFER", or "INVALID_REVISION" for ProposeRevision
+	Fields []*FieldError `json:"fields"` // Rejected fields, ordered by field name
+}
+
+func (e *OfferValidationError) Error() string {
+	errorBytes, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf("invalid offer: %d fields rejected", len(e.Fields))
+	}
+	return string(errorBytes)
+}
+
+// add records a rejected field
+func (e *OfferValidationError) add(field string, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// result returns the error ordered by field name, or nil if no field was rejected
+func (e *OfferValidationError) result() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	sort.SliceStable(e.Fields, func(i, j int) bool {
+		return e.Fields[i].Field < e.Fields[j].Field
+	})
+	return e
+}
+
+// parseOfferTransient validates the transient data of CreateOffer and fills the offer terms.
+// issuedAt is the transaction time, used for the default and the check of validUntil.
+func parseOfferTransient(transientData map[string][]byte, issuedAt time.Time, offer *Offer) error {
+	validation := &OfferValidationError{Code: "INVALID_OFFER"}
+
+	for key := range transientData {
+		if _, known := offerTransientFields[key]; !known {
+			validation.add(key, "unknown field")
+		}
+	}
+	for key, required := range offerTransientFields {
+		if _, exists := transientData[key]; required && !exists {
+			validation.add(key, "is required")
+		}
+	}
+
+	if value, exists := transientData["name"]; exists {
+		offer.Name = strings.TrimSpace(string(value))
+		if offer.Name == "" {
+			validation.add("name", "cannot be empty")
+		}
+	}
+	if value, exists := transientData["companyName"]; exists {
+		offer.CompanyName = strings.TrimSpace(string(value))
+		if offer.CompanyName == "" {
+			validation.add("companyName", "cannot be empty")
+		}
+	}
+
+	if value, exists := transientData["email"]; exists {
+		offer.Email = strings.TrimSpace(string(value))
+		address, err := mail.ParseAddress(offer.Email)
+		if err != nil || address.Address != offer.Email {
+			validation.add("email", "must be a plain address such as name@example.com")
+		}
+	}
+
+	var releaseDate, joiningDate time.Time
+	if value, exists := transientData["dateOfRelease"]; exists {
+		offer.DateOfRelease = string(value)
+		date, err := parseISODate(offer.DateOfRelease)
+		if err != nil {
+			validation.add("dateOfRelease", "must be an ISO-8601 date or timestamp")
+		}
+		releaseDate = date
+	}
+	if value, exists := transientData["dateOfJoining"]; exists {
+		offer.DateOfJoining = string(value)
+		date, err := parseISODate(offer.DateOfJoining)
+		if err != nil {
+			validation.add("dateOfJoining", "must be an ISO-8601 date or timestamp")
+		}
+		joiningDate = date
+	}
+	if !releaseDate.IsZero() && !joiningDate.IsZero() && !joiningDate.After(releaseDate) {
+		validation.add("dateOfJoining", "must be after dateOfRelease")
+	}
+
+	if value, exists := transientData["ctc"]; exists {
+		offer.Ctc = parseCtc(value, validation)
+	}
+
+	// The nonce salts the hashes of the terms, so that they cannot be guessed from the public ledger
+	if value, exists := transientData["offerNonce"]; exists {
+		if len(value) < offerNonceLength {
+			validation.add("offerNonce", "must carry at least %d random bytes", offerNonceLength)
+		} else {
+			offer.Nonce = hex.EncodeToString(value)
+		}
+	}
+
+	// The student must respond before validUntil, by default 30 days after issue
+	offer.ValidUntil = issuedAt.Add(defaultOfferValidity).Format(time.RFC3339)
+	if value, exists := transientData["validUntil"]; exists {
+		deadline, err := time.Parse(time.RFC3339, string(value))
+		if err != nil {
+			validation.add("validUntil", "must be an RFC3339 timestamp")
+		} else if !deadline.After(issuedAt) {
+			validation.add("validUntil", "must be after the time of issue")
+		} else {
+			offer.ValidUntil = deadline.UTC().Format(time.RFC3339)
+		}
+	}
+
+	return validation.result()
+}
+
+// parseRevisionTransient validates the transient data of ProposeRevision: a new ctc, a new
+// dateOfJoining after the offer's dateOfRelease, or both
+func parseRevisionTransient(transientData map[string][]byte, offer *Offer, revision *OfferRevision) error {
+	validation := &OfferValidationError{Code: "INVALID_REVISION"}
+
+	for key := range transientData {
+		if key != "ctc" && key != "dateOfJoining" {
+			validation.add(key, "unknown field")
+		}
+	}
+	if len(transientData) == 0 {
+		validation.add("ctc", "a revision must propose a ctc, a dateOfJoining or both")
+	}
+
+	if value, exists := transientData["ctc"]; exists {
+		revision.Ctc = parseCtc(value, validation)
+	}
+	if value, exists := transientData["dateOfJoining"]; exists {
+		revision.DateOfJoining = string(value)
+		joiningDate, err := parseISODate(revision.DateOfJoining)
+		if err != nil {
+			validation.add("dateOfJoining", "must be an ISO-8601 date or timestamp")
+		} else if releaseDate, err := parseISODate(offer.DateOfRelease); err == nil && !joiningDate.After(releaseDate) {
+			validation.add("dateOfJoining", "must be after dateOfRelease %s", offer.DateOfRelease)
+		}
+	}
+
+	return validation.result()
+}
+
+// parseCtc decodes a CTC JSON object, recording every problem in validation
+func parseCtc(value []byte, validation *OfferValidationError) *Ctc {
+	if len(bytes.TrimSpace(value)) == 0 || bytes.TrimSpace(value)[0] != '{' {
+		validation.add("ctc", "must be a JSON object with fixed, variable, currency and period")
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.DisallowUnknownFields()
+
+	var ctc Ctc
+	err := decoder.Decode(&ctc)
+	if err != nil {
+		validation.add("ctc", "could not be parsed: %v", err)
+		return nil
+	}
+
+	if ctc.Legacy != "" {
+		validation.add("ctc.legacy", "unknown field")
+	}
+	if math.IsNaN(ctc.Fixed) || math.IsInf(ctc.Fixed, 0) || ctc.Fixed < 0 {
+		validation.add("ctc.fixed", "must be a non-negative number")
+	}
+	if math.IsNaN(ctc.Variable) || math.IsInf(ctc.Variable, 0) || ctc.Variable < 0 {
+		validation.add("ctc.variable", "must be a non-negative number")
+	}
+	if ctc.Fixed+ctc.Variable <= 0 {
+		validation.add("ctc", "fixed and variable components cannot both be zero")
+	}
+	if !currencyPattern.MatchString(ctc.Currency) {
+		validation.add("ctc.currency", "must be an ISO 4217 code such as INR")
+	}
+	if ctc.Period != PeriodAnnual && ctc.Period != PeriodMonthly {
+		validation.add("ctc.period", "must be %q or %q", PeriodAnnual, PeriodMonthly)
+	}
+
+	return &ctc
+}
+
+// parseISODate accepts an ISO-8601 calendar date (2006-01-02) or an RFC3339 timestamp
+func parseISODate(value string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", value)
+	if err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,20 @@
+package contracts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCtcRejectsUnknownKeys(t *testing.T) {
+	validation := &OfferValidationError{Code: "INVALID_OFFER"}
+	parseCtc([]byte(`{"fixed":1,"variable":0,"currency":"INR","period":"annual","bonus":5}`), validation)
+	if validation.result() == nil || !strings.Contains(validation.Error(), "bonus") {
+		t.Fatalf("expected the bonus key to be rejected, got %v", validation.Fields)
+	}
+
+	validation = &OfferValidationError{Code: "INVALID_OFFER"}
+	ctc := parseCtc([]byte(`{"fixed":1,"variable":0,"currency":"INR","period":"annual"}`), validation)
+	if validation.result() != nil || ctc.Fixed != 1 {
+		t.Fatalf("expected a valid ctc, got %v", validation.Fields)
+	}
+}
@@ -0,0 +1,180 @@
+package contracts
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Result storage shared by every contract of the chaincode. Contracts in the same chaincode
+// read each other's state directly instead of going through InvokeChaincode. These helpers
+// apply no authorization; callers check access first.
+
+// Every institution keeps the marks, percentage and status of its results in its own private
+// collection, named after its MSP ID like the offer collections. Its members are the institution
+// and StudentMSP: transactions reading marks must be endorsed by a majority of organizations that
+// can read them, so company peers never store marks and learn them only through consented reads
+// or selective disclosure. The collection is defined when the institution is onboarded.
+const marksCollectionPrefix string = "marks_"
+
+// Transient key carrying the issuer's secret from which the salts of private marks are derived
+const resultSaltKey string = "resultSalt"
+
+// resultMarks is the private part of a result. The salt keeps the public hash of the private
+// data from being matched against every possible combination of marks.
+type resultMarks struct {
+	TotalMarks    float64 `json:"totalMarks"`
+	ObtainedMarks float64 `json:"obtainedMarks"`
+	Percentage    float64 `json:"percentage"`
+	Status        string  `json:"status"`
+	Salt          string  `json:"salt"`
+}
+
+// getResult reads the public part of a result from the world state without applying read
+// authorization. Marks are left empty until loaded with loadResultMarks.
+func getResult(ctx contractapi.TransactionContextInterface, resultId string) (*Result, error) {
+	resultBytes, err := ctx.GetStub().GetState(resultId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if resultBytes == nil {
+		return nil, newChaincodeError(CodeNotFound, "the result with ID %s does not exist", resultId)
+	}
+
+	var result Result
+	err = json.Unmarshal(resultBytes, &result)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal result: %v", err)
+	}
+
+	return &result, nil
+}
+
+// marksCollectionName returns the private collection holding the marks issued by an institution
+func marksCollectionName(issuerMSP string) string {
+	return marksCollectionPrefix + issuerMSP
+}
+
+// putResult writes the public part of a result to the world state under its ID, and its marks to
+// the marks collection of its issuer. Results stored before marks became private carry no salt; their
+// marks stay in public state until they are sealed.
+func putResult(ctx contractapi.TransactionContextInterface, result *Result) error {
+	public := *result
+	if result.salt != "" {
+		marksBytes, err := json.Marshal(resultMarks{
+			TotalMarks:    result.TotalMarks,
+			ObtainedMarks: result.ObtainedMarks,
+			Percentage:    result.Percentage,
+			Status:        result.Status,
+			Salt:          result.salt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal result marks: %v", err)
+		}
+		err = ctx.GetStub().PutPrivateData(marksCollectionName(result.IssuerMSP), result.ResultId, marksBytes)
+		if err != nil {
+			return fmt.Errorf("could not write result marks to the private collection: %v", err)
+		}
+
+		public.TotalMarks = 0
+		public.ObtainedMarks = 0
+		public.Percentage = 0
+		public.Status = ""
+	}
+
+	resultBytes, err := json.Marshal(public)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(result.ResultId, resultBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store result in world state: %v", err)
+	}
+	return nil
+}
+
+// deleteResult removes a result and its private marks
+func deleteResult(ctx contractapi.TransactionContextInterface, result *Result) error {
+	err := ctx.GetStub().DelState(result.ResultId)
+	if err != nil {
+		return fmt.Errorf("failed to delete result: %v", err)
+	}
+	if result.IssuerMSP == "" {
+		return nil
+	}
+	err = ctx.GetStub().DelPrivateData(marksCollectionName(result.IssuerMSP), result.ResultId)
+	if err != nil {
+		return fmt.Errorf("failed to delete result marks: %v", err)
+	}
+	return nil
+}
+
+// loadResultMarks fills in the marks of results from the marks collections of their issuers. Peers
+// outside a collection cannot read it, so transactions that need marks must be endorsed by its
+// members.
+func loadResultMarks(ctx contractapi.TransactionContextInterface, results ...*Result) error {
+	for _, result := range results {
+		// Results stored before issuers were recorded have never had private marks
+		if result.IssuerMSP == "" {
+			continue
+		}
+		collection := marksCollectionName(result.IssuerMSP)
+		marksBytes, err := ctx.GetStub().GetPrivateData(collection, result.ResultId)
+		if err != nil {
+			return fmt.Errorf("marks of result %s can only be read on peers of the %s collection: %v", result.ResultId, collection, err)
+		}
+		if marksBytes == nil {
+			// Results stored before marks became private still carry them publicly
+			if result.Status != "" {
+				continue
+			}
+			return fmt.Errorf("marks of result %s are not available on this peer", result.ResultId)
+		}
+
+		var marks resultMarks
+		err = json.Unmarshal(marksBytes, &marks)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal result marks: %v", err)
+		}
+		result.TotalMarks = marks.TotalMarks
+		result.ObtainedMarks = marks.ObtainedMarks
+		result.Percentage = marks.Percentage
+		result.Status = marks.Status
+		result.salt = marks.Salt
+	}
+	return nil
+}
+
+// resultSalt derives the salt of a result's private marks from the resultSalt transient secret,
+// so that every endorser derives the same one
+func resultSalt(ctx contractapi.TransactionContextInterface, resultId string) (string, error) {
+	transientData, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch transient data. %s", err)
+	}
+	secret, exists := transientData[resultSaltKey]
+	if !exists || len(secret) < 32 {
+		return "", newChaincodeError(CodeInvalidArgument, "the %s transient field must carry at least 32 random bytes", resultSaltKey)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(resultId))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// getResultsByStudent reads every result issued to a student, revoked ones included
+func getResultsByStudent(ctx contractapi.TransactionContextInterface, studentId string) ([]*Result, error) {
+	queryString := fmt.Sprintf(`{"selector":{"assetType":"Result","studentId":%q}}`, studentId)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch results of student %s: %v", studentId, err)
+	}
+	defer resultsIterator.Close()
+
+	return resultIteratorFunction(resultsIterator)
+}
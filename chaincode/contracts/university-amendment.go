@@ -0,0 +1,318 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Object type of the composite key (resultId, txId) for result amendments
+const amendmentObjectType string = "amendment"
+
+// Reason codes accepted by AmendResult
+var amendmentReasonCodes = map[string]bool{
+	"DATA_ENTRY_ERROR": true, // Marks or student ID were keyed in incorrectly
+	"RE_EVALUATION":    true, // Script was re-evaluated after the result was issued
+	"GRADE_APPEAL":     true, // Student appeal upheld by the examination board
+	"ADMINISTRATIVE":   true, // Any other correction approved by the registrar
+}
+
+// Result fields whose amendment values are held in the marks collection of the issuer
+var privateResultFields = map[string]bool{
+	"totalMarks":    true,
+	"obtainedMarks": true,
+	"percentage":    true,
+	"status":        true,
+}
+
+// ResultChanges lists the fields an amendment may change; omitted fields are left untouched
+type ResultChanges struct {
+	StudentId     *string  `json:"studentId,omitempty"`
+	TotalMarks    *float64 `json:"totalMarks,omitempty"`
+	ObtainedMarks *float64 `json:"obtainedMarks,omitempty"`
+}
+
+// FieldChange is the before/after value of a single amended field. Values of marks are kept in
+// the marks collection of the issuer and only filled in on its member peers.
+type FieldChange struct {
+	Field    string `json:"field"`                                   // JSON name of the changed field
+	Previous string `json:"previous,omitempty" metadata:",optional"` // Value before the amendment
+	Current  string `json:"current,omitempty" metadata:",optional"`  // Value after the amendment
+}
+
+// amendmentMarks holds the values of the marks changed by an amendment, salted like the result's marks
+type amendmentMarks struct {
+	Changes []*FieldChange `json:"changes"`
+	Salt    string         `json:"salt"`
+}
+
+// ResultAmendment records a justified change to a result
+type ResultAmendment struct {
+	AssetType     string         `json:"assetType"`     // Asset type ("ResultAmendment")
+	ResultId      string         `json:"resultId"`      // Amended result
+	TxId          string         `json:"txId"`          // Transaction that applied the amendment
+	ReasonCode    string         `json:"reasonCode"`    // One of amendmentReasonCodes
+	Justification string         `json:"justification"` // Free-text justification from the registrar
+	AmendedBy     string         `json:"amendedBy"`     // Client identity that made the change
+	AmendedByMSP  string         `json:"amendedByMsp"`  // MSP of the amending identity
+	AmendedAt     string         `json:"amendedAt"`     // Transaction timestamp (RFC3339)
+	Changes       []*FieldChange `json:"changes"`       // Previous and new values of each changed field
+}
+
+// AmendResult corrects fields of a result, recording the previous values and a mandatory justification.
+// changes is a JSON object with any of studentId, totalMarks and obtainedMarks. The amendment bumps the
+// result's version, which invalidates its disclosure commitments until they are published again.
+// Results whose marks are still public are sealed, salted from the "resultSalt" transient field.
+func (r *ResultContract) AmendResult(ctx contractapi.TransactionContextInterface, resultId string, changes string, reasonCode string, justification string) (string, error) {
+	if !amendmentReasonCodes[reasonCode] {
+		return "", newChaincodeError(CodeInvalidArgument, "invalid reason code %q", reasonCode)
+	}
+	if strings.TrimSpace(justification) == "" {
+		return "", newChaincodeError(CodeInvalidArgument, "a justification is required to amend a result")
+	}
+
+	var requested ResultChanges
+	decoder := json.NewDecoder(strings.NewReader(changes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&requested); err != nil {
+		return "", newChaincodeError(CodeInvalidArgument, "could not parse changes: %v", err)
+	}
+
+	_, err := authorize(ctx, ActionResultAmend)
+	if err != nil {
+		return "", err
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+
+	result, err := r.ReadResult(ctx, resultId)
+	if err != nil {
+		return "", err
+	}
+	if result.Revoked {
+		return "", newChaincodeError(CodeConflict, "result %s has been revoked and cannot be amended", resultId)
+	}
+	err = authorizeIssuer(ctx, result)
+	if err != nil {
+		return "", err
+	}
+
+	previous := *result
+	if requested.StudentId != nil {
+		if strings.TrimSpace(*requested.StudentId) == "" {
+			return "", newChaincodeError(CodeInvalidArgument, "studentId cannot be empty")
+		}
+		result.StudentId = *requested.StudentId
+	}
+	if requested.TotalMarks != nil {
+		result.TotalMarks = *requested.TotalMarks
+	}
+	if requested.ObtainedMarks != nil {
+		result.ObtainedMarks = *requested.ObtainedMarks
+	}
+	if err := validateMarks(result.TotalMarks, result.ObtainedMarks); err != nil {
+		return "", err
+	}
+
+	// Percentage and status are always re-derived on-chain
+	threshold, err := r.GetPassThreshold(ctx, result.IssuerMSP)
+	if err != nil {
+		return "", err
+	}
+	result.Percentage = computePercentage(result.TotalMarks, result.ObtainedMarks)
+	result.Status = computeStatus(result.Percentage, threshold)
+
+	diff := diffResults(&previous, result)
+	if len(diff) == 0 {
+		return "", newChaincodeError(CodeInvalidArgument, "the amendment does not change result %s", resultId)
+	}
+
+	if result.salt == "" {
+		result.salt, err = resultSalt(ctx, resultId)
+		if err != nil {
+			return "", err
+		}
+	}
+	result.Version++
+
+	amendedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// Mark values go to the private collection; the public record only names the changed fields
+	var publicChanges, privateChanges []*FieldChange
+	for _, change := range diff {
+		if privateResultFields[change.Field] {
+			privateChanges = append(privateChanges, change)
+			change = &FieldChange{Field: change.Field}
+		}
+		publicChanges = append(publicChanges, change)
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	amendment := ResultAmendment{
+		AssetType:     "ResultAmendment",
+		ResultId:      resultId,
+		TxId:          txID,
+		ReasonCode:    reasonCode,
+		Justification: justification,
+		AmendedBy:     clientID,
+		AmendedByMSP:  clientOrgID,
+		AmendedAt:     amendedAt,
+		Changes:       publicChanges,
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(amendmentObjectType, []string{resultId, txID})
+	if err != nil {
+		return "", fmt.Errorf("could not create amendment key: %v", err)
+	}
+
+	amendmentBytes, err := json.Marshal(amendment)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal amendment: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(key, amendmentBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to store amendment in world state: %v", err)
+	}
+
+	if len(privateChanges) > 0 {
+		marksBytes, err := json.Marshal(amendmentMarks{Changes: privateChanges, Salt: result.salt})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal amended marks: %v", err)
+		}
+		err = ctx.GetStub().PutPrivateData(marksCollectionName(result.IssuerMSP), key, marksBytes)
+		if err != nil {
+			return "", fmt.Errorf("could not write amended marks to the private collection: %v", err)
+		}
+	}
+
+	err = putResult(ctx, result)
+	if err != nil {
+		return "", err
+	}
+
+	err = invalidateDisclosure(ctx, result)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Successfully amended result %v", resultId), nil
+}
+
+// GetResultAmendments retrieves every amendment recorded against a result
+func (r *ResultContract) GetResultAmendments(ctx contractapi.TransactionContextInterface, resultId string) ([]*ResultAmendment, error) {
+	// Amendments are visible to whoever may read the result
+	result, err := getResult(ctx, resultId)
+	if err != nil {
+		return nil, err
+	}
+	err = authorizeResultRead(ctx, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return getResultAmendments(ctx, resultId)
+}
+
+// getResultAmendments reads the amendments of a result without applying read authorization, filling
+// in the changed marks from the marks collection of the issuer
+func getResultAmendments(ctx contractapi.TransactionContextInterface, resultId string) ([]*ResultAmendment, error) {
+	amendmentsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(amendmentObjectType, []string{resultId})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch amendments: %v", err)
+	}
+	defer amendmentsIterator.Close()
+
+	var amendments []*ResultAmendment
+	for amendmentsIterator.HasNext() {
+		queryResult, err := amendmentsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch amendment: %v", err)
+		}
+
+		var amendment ResultAmendment
+		err = json.Unmarshal(queryResult.Value, &amendment)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal amendment: %v", err)
+		}
+		err = loadAmendmentMarks(ctx, queryResult.Key, &amendment)
+		if err != nil {
+			return nil, err
+		}
+		amendments = append(amendments, &amendment)
+	}
+
+	return amendments, nil
+}
+
+// loadAmendmentMarks fills in the private values of the marks an amendment changed. Amendments
+// recorded before marks became private carry their values publicly and have no private entry.
+// Only the issuer may amend a result, so its values are kept in the collection of the amending
+// institution.
+func loadAmendmentMarks(ctx contractapi.TransactionContextInterface, key string, amendment *ResultAmendment) error {
+	if amendment.AmendedByMSP == "" {
+		return nil
+	}
+	collection := marksCollectionName(amendment.AmendedByMSP)
+	marksBytes, err := ctx.GetStub().GetPrivateData(collection, key)
+	if err != nil {
+		return fmt.Errorf("marks of amendment %s can only be read on peers of the %s collection: %v", amendment.TxId, collection, err)
+	}
+	if marksBytes == nil {
+		return nil
+	}
+
+	var marks amendmentMarks
+	err = json.Unmarshal(marksBytes, &marks)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal amended marks: %v", err)
+	}
+	values := make(map[string]*FieldChange)
+	for _, change := range marks.Changes {
+		values[change.Field] = change
+	}
+	for _, change := range amendment.Changes {
+		if value, ok := values[change.Field]; ok {
+			change.Previous = value.Previous
+			change.Current = value.Current
+		}
+	}
+	return nil
+}
+
+// diffResults lists the fields that differ between two versions of a result
+func diffResults(previous *Result, current *Result) []*FieldChange {
+	var changes []*FieldChange
+	addChange := func(field string, before string, after string) {
+		if before != after {
+			changes = append(changes, &FieldChange{Field: field, Previous: before, Current: after})
+		}
+	}
+
+	addChange("studentId", previous.StudentId, current.StudentId)
+	addChange("totalMarks", formatNumber(previous.TotalMarks), formatNumber(current.TotalMarks))
+	addChange("obtainedMarks", formatNumber(previous.ObtainedMarks), formatNumber(current.ObtainedMarks))
+	addChange("percentage", formatNumber(previous.Percentage), formatNumber(current.Percentage))
+	addChange("status", previous.Status, current.Status)
+
+	return changes
+}
+
+// formatNumber renders a number with the shortest exact representation
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
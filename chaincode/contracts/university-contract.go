@@ -3,6 +3,8 @@ package contracts
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 	"strings"
 
@@ -24,31 +26,60 @@ type PaginatedQueryResult struct {
 
 // HistoryQueryResult contains the result along with its transaction history
 type HistoryQueryResult struct {
-	Record    *Result `json:"record"`    // The result record
-	TxId      string  `json:"txId"`      // Transaction ID
-	Timestamp string  `json:"timestamp"` // Timestamp of the transaction
-	IsDelete  bool    `json:"isDelete"`  // Indicates if the record was deleted
+	Record    *Result          `json:"record"`                                 // The result record
+	TxId      string           `json:"txId"`                                   // Transaction ID
+	Timestamp string           `json:"timestamp"`                              // Timestamp of the transaction
+	IsDelete  bool             `json:"isDelete"`                               // Indicates if the record was deleted
+	Amendment *ResultAmendment `json:"amendment,omitempty" metadata:",optional"` // Amendment written by this transaction, if any
 }
 
 // Result represents the structure of a student's academic result
 type Result struct {
-	AssetType      string `json:"assetType"`      // Asset type ("Result")
-	ResultId       string `json:"resultId"`       // Unique identifier for the result
-	StudentId      string `json:"studentId"`      // Identifier for the student
-	TotalMarks     string `json:"totalMarks"`     // Total possible marks
-	ObtainedMarks  string `json:"obtainedMarks"`  // Marks obtained by the student
-	Percentage     string `json:"percentage"`     // Calculated percentage
-	Status         string `json:"status"`         // Pass/Fail status
+	AssetType        string      `json:"assetType"`                                     // Asset type ("Result")
+	ResultId         string      `json:"resultId"`                                      // Unique identifier for the result
+	StudentId        string      `json:"studentId"`                                     // Identifier for the student
+	IssuerMSP        string      `json:"issuerMsp"`                                     // MSP ID of the registered institution that issued the result
+	IssuerName       string      `json:"issuerName"`                                    // Name of the issuing institution at the time of issue
+	TotalMarks       float64     `json:"totalMarks,omitempty" metadata:",optional"`       // Total possible marks, held in the marks collection of the issuer
+	ObtainedMarks    float64     `json:"obtainedMarks,omitempty" metadata:",optional"`    // Marks obtained by the student, held in the marks collection of the issuer
+	Percentage       float64     `json:"percentage,omitempty" metadata:",optional"`       // Percentage computed by the chaincode, held in the marks collection of the issuer
+	Status           string      `json:"status,omitempty" metadata:",optional"`           // Pass/Fail status computed by the chaincode, held in the marks collection of the issuer
+	Version          int         `json:"version"`                                       // Incremented by every amendment
+	Revoked          bool        `json:"revoked"`                                       // Whether the issuer has withdrawn this result
+	Revocation       *Revocation `json:"revocation,omitempty" metadata:",optional"`       // Details of the withdrawal, set when revoked
+	ReplacesResultId string      `json:"replacesResultId,omitempty" metadata:",optional"` // ID of the revoked result this one reissues
+
+	salt string // Salt of the private marks, set once they are loaded or created
 }
 
 // EventData represents metadata for blockchain events
 type EventData struct {
-	Type   string // Type of event
-	Percentage string // Percentage of the event
+	Type     string // Type of event
+	ResultId string // Result the event is about
 }
 
+// Result status values derived on-chain from the pass threshold
+const (
+	StatusPass string = "Pass"
+	StatusFail string = "Fail"
+)
+
+// Object type and attribute of the composite key (passThreshold, issuerMsp) holding each issuer's pass threshold
+const (
+	configObjectType   string = "config"
+	passThresholdField string = "passThreshold"
+)
+
+// Pass threshold used until an institution sets its own on the ledger
+const defaultPassThreshold float64 = 40
+
 // ResultExists checks if a result with the given ID already exists in the blockchain
 func (r *ResultContract) ResultExists(ctx contractapi.TransactionContextInterface, resultId string) (bool, error) {
+	_, err := authorize(ctx, ActionResultRead)
+	if err != nil {
+		return false, err
+	}
+
 	data, err := ctx.GetStub().GetState(resultId)
 
 	if err != nil {
@@ -57,126 +88,432 @@ func (r *ResultContract) ResultExists(ctx contractapi.TransactionContextInterfac
 	return data != nil, nil
 }
 
-// CreateResult adds a new result to the blockchain with access control
-func (r *ResultContract) CreateResult(ctx contractapi.TransactionContextInterface, resultId string, studentId string, totalMarks string, obtainedMarks string, percentage string, status string) (string, error) {
+// CreateResult adds a new result to the blockchain with access control.
+// The percentage and pass/fail status are derived from the marks on-chain. The marks are stored
+// in the marks collection of the issuer, salted from the "resultSalt" transient field.
+func (r *ResultContract) CreateResult(ctx contractapi.TransactionContextInterface, resultId string, studentId string, totalMarks float64, obtainedMarks float64) (string, error) {
 	// Validate input parameters
 	if strings.TrimSpace(resultId) == "" || strings.TrimSpace(studentId) == "" {
-		return "", fmt.Errorf("resultId and studentId cannot be empty")
+		return "", newChaincodeError(CodeInvalidArgument, "resultId and studentId cannot be empty")
+	}
+	if err := validateMarks(totalMarks, obtainedMarks); err != nil {
+		return "", err
 	}
 
 	// Verify client organization identity
-	clientOrgId, err := ctx.GetClientIdentity().GetMSPID()
+	_, err := authorize(ctx, ActionResultCreate)
 	if err != nil {
-		return "", fmt.Errorf("failed to retrieve client identity: %v", err)
+		return "", err
 	}
 
-	// Restrict result creation to specific organization
-	if clientOrgId != "UniversityMSP" {
-		return "", fmt.Errorf("unauthorized organization %v cannot create results", clientOrgId)
+	result, err := r.newResult(ctx, resultId, studentId, totalMarks, obtainedMarks)
+	if err != nil {
+		return "", err
+	}
+
+	// Store the result in the world state
+	err = putResult(ctx, result)
+	if err != nil {
+		return "", err
 	}
 
+	// Trigger an event after creating the result; marks stay out of public events
+	eventData := EventData{
+		Type:     "Result creation",
+		ResultId: result.ResultId,
+	}
+	eventBytes, _ := json.Marshal(eventData)
+	ctx.GetStub().SetEvent("CreateResult", eventBytes)
+
+	return fmt.Sprintf("Successfully added result %v", resultId), nil
+}
+
+// newResult validates that resultId is free and builds a Result with on-chain derived percentage and status
+func (r *ResultContract) newResult(ctx contractapi.TransactionContextInterface, resultId string, studentId string, totalMarks float64, obtainedMarks float64) (*Result, error) {
 	// Check if result already exists
 	exists, err := r.ResultExists(ctx, resultId)
 	if err != nil {
-		return "", fmt.Errorf("could not fetch result: %s", err)
+		return nil, err
 	}
 	if exists {
-		return "", fmt.Errorf("result with ID %s already exists", resultId)
+		return nil, newChaincodeError(CodeConflict, "result with ID %s already exists", resultId)
+	}
+
+	// The calling institution is stamped as issuer
+	issuerMSP, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	issuer, err := getInstitution(ctx, issuerMSP)
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return nil, newChaincodeError(CodeNotFound, "%v is not a registered institution", issuerMSP)
+	}
+
+	// Derive percentage and status from the issuer's pass threshold
+	threshold, err := r.GetPassThreshold(ctx, issuerMSP)
+	if err != nil {
+		return nil, err
+	}
+	percentage := computePercentage(totalMarks, obtainedMarks)
+	salt, err := resultSalt(ctx, resultId)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create a new Result
-	result := Result{
+	return &Result{
 		AssetType:     "Result",
 		ResultId:      resultId,
 		StudentId:     studentId,
+		IssuerMSP:     issuer.MspId,
+		IssuerName:    issuer.Name,
 		TotalMarks:    totalMarks,
 		ObtainedMarks: obtainedMarks,
 		Percentage:    percentage,
-		Status:        status,
+		Status:        computeStatus(percentage, threshold),
+		salt:          salt,
+	}, nil
+}
+
+// SetPassThreshold stores the minimum percentage the calling institution requires for a Pass status
+func (r *ResultContract) SetPassThreshold(ctx contractapi.TransactionContextInterface, threshold float64) (string, error) {
+	_, err := authorize(ctx, ActionResultConfigure)
+	if err != nil {
+		return "", err
 	}
 
-	// Store the result in the world state
-	resultBytes, err := json.Marshal(result)
+	if math.IsNaN(threshold) || threshold < 0 || threshold > 100 {
+		return "", newChaincodeError(CodeInvalidArgument, "pass threshold must be between 0 and 100, got %v", threshold)
+	}
+
+	issuerMSP, err := ctx.GetClientIdentity().GetMSPID()
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %v", err)
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
 	}
 
-	err = ctx.GetStub().PutState(resultId, resultBytes)
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{passThresholdField, issuerMSP})
 	if err != nil {
-		return "", fmt.Errorf("failed to store result in world state: %v", err)
+		return "", fmt.Errorf("could not create config key: %v", err)
 	}
 
-	// Trigger an event after creating the result
-	eventData := EventData{
-		Type:   "Result creation",
-		Percentage: percentage,
+	err = ctx.GetStub().PutState(key, []byte(strconv.FormatFloat(threshold, 'f', -1, 64)))
+	if err != nil {
+		return "", fmt.Errorf("failed to store pass threshold: %v", err)
 	}
-	eventBytes, _ := json.Marshal(eventData)
-	ctx.GetStub().SetEvent("CreateResult", eventBytes)
 
-	return fmt.Sprintf("Successfully added result %v", resultId), nil
+	return fmt.Sprintf("Pass threshold of %v set to %v%%", issuerMSP, threshold), nil
+}
+
+// GetPassThreshold returns the pass threshold of an issuing institution, or the default when none is set
+func (r *ResultContract) GetPassThreshold(ctx contractapi.TransactionContextInterface, issuerMSP string) (float64, error) {
+	_, err := authorize(ctx, ActionResultRead)
+	if err != nil {
+		return 0, err
+	}
+
+	return getPassThreshold(ctx, issuerMSP)
+}
+
+// getPassThreshold reads the pass threshold of an institution without applying read authorization
+func getPassThreshold(ctx contractapi.TransactionContextInterface, issuerMSP string) (float64, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{passThresholdField, issuerMSP})
+	if err != nil {
+		return 0, fmt.Errorf("could not create config key: %v", err)
+	}
+
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read pass threshold: %v", err)
+	}
+	if data == nil {
+		return defaultPassThreshold, nil
+	}
+
+	threshold, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse stored pass threshold: %v", err)
+	}
+
+	return threshold, nil
 }
 
-// ReadResult retrieves an instance of Result from the world state
+// MigrateResults rewrites results stored with string-typed marks into the typed
+// layout, recomputing percentage and status from the marks, and assigns results that
+// never recorded an issuer to the institution named as argument. Like other registry
+// changes it applies once a majority of the governing organizations requested it.
+// Migrated marks stay in public state until the issuer seals them with SealResults.
+func (r *ResultContract) MigrateResults(ctx contractapi.TransactionContextInterface, issuerMsp string) (string, error) {
+	_, err := authorize(ctx, ActionInstitutionGovern)
+	if err != nil {
+		return "", err
+	}
+
+	issuer, err := getInstitution(ctx, issuerMsp)
+	if err != nil {
+		return "", err
+	}
+	if issuer == nil {
+		return "", newChaincodeError(CodeNotFound, "the institution %s is not registered", issuerMsp)
+	}
+
+	approvals, err := approveChange(ctx, "MigrateResults", issuerMsp)
+	if err != nil {
+		return "", err
+	}
+	if approvals < approvalThreshold() {
+		return pendingApprovalMessage("the migration of results to "+issuerMsp, approvals), nil
+	}
+
+	threshold, err := getPassThreshold(ctx, issuer.MspId)
+	if err != nil {
+		return "", err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(`{"selector":{"assetType":"Result"}}`)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch results: %s", err)
+	}
+	defer resultsIterator.Close()
+
+	migrated := 0
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("could not fetch result: %s", err)
+		}
+
+		var legacy map[string]interface{}
+		err = json.Unmarshal(queryResult.Value, &legacy)
+		if err != nil {
+			return "", fmt.Errorf("could not unmarshal result %s: %v", queryResult.Key, err)
+		}
+
+		// Results of other institutions are left to them
+		issuerMSP, _ := legacy["issuerMsp"].(string)
+		if issuerMSP != "" && issuerMSP != issuer.MspId {
+			continue
+		}
+
+		// Records whose marks are already numeric only need an issuer
+		_, totalIsString := legacy["totalMarks"].(string)
+		_, obtainedIsString := legacy["obtainedMarks"].(string)
+		if !totalIsString && !obtainedIsString {
+			if issuerMSP != "" {
+				continue
+			}
+			var result Result
+			err = json.Unmarshal(queryResult.Value, &result)
+			if err != nil {
+				return "", fmt.Errorf("could not unmarshal result %s: %v", queryResult.Key, err)
+			}
+			result.IssuerMSP = issuer.MspId
+			result.IssuerName = issuer.Name
+			err = putResult(ctx, &result)
+			if err != nil {
+				return "", err
+			}
+			migrated++
+			continue
+		}
+
+		totalMarks, err := parseLegacyNumber(legacy["totalMarks"])
+		if err != nil {
+			return "", fmt.Errorf("result %s has invalid totalMarks: %v", queryResult.Key, err)
+		}
+		obtainedMarks, err := parseLegacyNumber(legacy["obtainedMarks"])
+		if err != nil {
+			return "", fmt.Errorf("result %s has invalid obtainedMarks: %v", queryResult.Key, err)
+		}
+		if err := validateMarks(totalMarks, obtainedMarks); err != nil {
+			return "", fmt.Errorf("result %s cannot be migrated: %s", queryResult.Key, errorMessage(err))
+		}
+
+		percentage := computePercentage(totalMarks, obtainedMarks)
+		studentId, _ := legacy["studentId"].(string)
+		result := Result{
+			AssetType:     "Result",
+			ResultId:      queryResult.Key,
+			StudentId:     studentId,
+			IssuerMSP:     issuer.MspId,
+			IssuerName:    issuer.Name,
+			TotalMarks:    totalMarks,
+			ObtainedMarks: obtainedMarks,
+			Percentage:    percentage,
+			Status:        computeStatus(percentage, threshold),
+		}
+
+		err = putResult(ctx, &result)
+		if err != nil {
+			return "", err
+		}
+		migrated++
+	}
+
+	return fmt.Sprintf("Successfully migrated %d results to %v", migrated, issuer.MspId), nil
+}
+
+// SealResults moves the marks of the calling institution's results still held in public
+// state to its marks collection, salted from the "resultSalt" transient field
+func (r *ResultContract) SealResults(ctx contractapi.TransactionContextInterface) (string, error) {
+	_, err := authorize(ctx, ActionResultConfigure)
+	if err != nil {
+		return "", err
+	}
+
+	issuerMSP, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+
+	queryString := fmt.Sprintf(`{"selector":{"assetType":"Result","issuerMsp":%q}}`, issuerMSP)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch results: %s", err)
+	}
+	defer resultsIterator.Close()
+
+	results, err := resultIteratorFunction(resultsIterator)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := 0
+	for _, result := range results {
+		// Sealed results carry no status in public state
+		if result.Status == "" {
+			continue
+		}
+		result.salt, err = resultSalt(ctx, result.ResultId)
+		if err != nil {
+			return "", err
+		}
+		err = putResult(ctx, result)
+		if err != nil {
+			return "", err
+		}
+		sealed++
+	}
+
+	return fmt.Sprintf("Successfully sealed %d results", sealed), nil
+}
+
+// validateMarks rejects negative marks and obtained marks above the total
+func validateMarks(totalMarks float64, obtainedMarks float64) error {
+	if math.IsNaN(totalMarks) || math.IsInf(totalMarks, 0) || totalMarks <= 0 {
+		return newChaincodeError(CodeInvalidArgument, "totalMarks must be a positive number, got %v", totalMarks)
+	}
+	if math.IsNaN(obtainedMarks) || math.IsInf(obtainedMarks, 0) || obtainedMarks < 0 {
+		return newChaincodeError(CodeInvalidArgument, "obtainedMarks cannot be negative, got %v", obtainedMarks)
+	}
+	if obtainedMarks > totalMarks {
+		return newChaincodeError(CodeInvalidArgument, "obtainedMarks %v cannot exceed totalMarks %v", obtainedMarks, totalMarks)
+	}
+	return nil
+}
+
+// computePercentage returns the percentage rounded to two decimal places
+func computePercentage(totalMarks float64, obtainedMarks float64) float64 {
+	return math.Round(obtainedMarks/totalMarks*10000) / 100
+}
+
+// computeStatus returns Pass when the percentage meets the threshold
+func computeStatus(percentage float64, threshold float64) string {
+	if percentage >= threshold {
+		return StatusPass
+	}
+	return StatusFail
+}
+
+// parseLegacyNumber reads a mark stored either as a JSON number or as a string such as "90" or "90%"
+func parseLegacyNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "%")), 64)
+	default:
+		return 0, fmt.Errorf("unsupported value %v", value)
+	}
+}
+
+// ReadResult retrieves an instance of Result from the world state with its private marks
 func (r *ResultContract) ReadResult(ctx contractapi.TransactionContextInterface, resultId string) (*Result, error) {
-	resultBytes, err := ctx.GetStub().GetState(resultId)
+	result, err := getResult(ctx, resultId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
+		return nil, err
 	}
-	if resultBytes == nil {
-		return nil, fmt.Errorf("the result with ID %s does not exist", resultId)
+
+	// Companies need an active consent grant from the student
+	err = authorizeResultRead(ctx, result)
+	if err != nil {
+		return nil, err
 	}
 
-	var result Result
-	err = json.Unmarshal(resultBytes, &result)
+	err = loadResultMarks(ctx, result)
 	if err != nil {
-		return nil, fmt.Errorf("could not unmarshal result: %v", err)
+		return nil, err
 	}
 
-	return &result, nil
+	return result, nil
 }
 
 // DeleteResult removes the result from the world state
 func (r *ResultContract) DeleteResult(ctx contractapi.TransactionContextInterface, resultId string) (string, error) {
 	// Verify client organization identity
-	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	_, err := authorize(ctx, ActionResultDelete)
 	if err != nil {
-		return "", fmt.Errorf("could not fetch client identity: %s", err)
+		return "", err
 	}
 
-	// Allow deletion only for UniversityMSP
-	if clientOrgID != "UniversityMSP" {
-		return "", fmt.Errorf("user under the following MSPID: %v can't perform this action", clientOrgID)
+	// Check if the result exists
+	result, err := getResult(ctx, resultId)
+	if err != nil {
+		return "", err
 	}
 
-	// Check if the result exists
-	exists, err := r.ResultExists(ctx, resultId)
+	// Only the issuing institution may delete its results
+	err = authorizeIssuer(ctx, result)
 	if err != nil {
-		return "", fmt.Errorf("could not check result existence: %s", err)
-	} else if !exists {
-		return "", fmt.Errorf("the result with ID %s does not exist", resultId)
+		return "", err
 	}
 
-	// Delete the result from the ledger
-	err = ctx.GetStub().DelState(resultId)
+	// Delete the result and its marks from the ledger
+	err = deleteResult(ctx, result)
 	if err != nil {
-		return "", fmt.Errorf("failed to delete result: %v", err)
+		return "", err
 	}
 
 	return fmt.Sprintf("Successfully deleted result with ID %s", resultId), nil
 }
 
 func (r *ResultContract) GetResultsByRange(ctx contractapi.TransactionContextInterface, startKey, endKey string) ([]*Result, error) {
+	_, err := authorize(ctx, ActionResultRead)
+	if err != nil {
+		return nil, err
+	}
+
 	resultsIterator, err := ctx.GetStub().GetStateByRange(startKey, endKey)
 	if err != nil {
 		return nil, fmt.Errorf("could not fetch the data by range. %s", err)
 	}
 	defer resultsIterator.Close()
 
-	return resultIteratorFunction(resultsIterator)
+	results, err := resultIteratorFunction(resultsIterator)
+	if err != nil {
+		return nil, err
+	}
+
+	return readableResultsWithMarks(ctx, results)
 }
 // GetAllResults retrieves all results
 func (r *ResultContract) GetAllResults(ctx contractapi.TransactionContextInterface) ([]*Result, error) {
+	_, err := authorize(ctx, ActionResultRead)
+	if err != nil {
+		return nil, err
+	}
+
 	queryString := `{"selector":{"assetType":"Result"}}`
 
 	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
@@ -201,7 +538,7 @@ func (r *ResultContract) GetAllResults(ctx contractapi.TransactionContextInterfa
 		results = append(results, &result)
 	}
 
-	return results, nil
+	return readableResultsWithMarks(ctx, results)
 }
 
 func resultIteratorFunction(resultsIterator shim.StateQueryIteratorInterface) ([]*Result, error) {
@@ -224,12 +561,33 @@ func resultIteratorFunction(resultsIterator shim.StateQueryIteratorInterface) ([
 
 // GetResultHistory retrieves the history of a result
 func (r *ResultContract) GetResultHistory(ctx contractapi.TransactionContextInterface, resultId string) ([]*HistoryQueryResult, error) {
+	// History is visible to whoever may read the current result; deleted results only to university staff
+	current, err := getResult(ctx, resultId)
+	if err == nil {
+		err = authorizeResultRead(ctx, current)
+	} else if role, authErr := authorize(ctx, ActionResultRead); authErr == nil && (role == RoleRegistrar || role == RoleAuditor) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	resultsIterator, err := ctx.GetStub().GetHistoryForKey(resultId)
 	if err != nil {
 		return nil, fmt.Errorf("could not fetch result history: %v", err)
 	}
 	defer resultsIterator.Close()
 
+	// Index amendments by transaction so each diff sits next to the state it produced
+	amendments, err := getResultAmendments(ctx, resultId)
+	if err != nil {
+		return nil, err
+	}
+	amendmentsByTx := make(map[string]*ResultAmendment)
+	for _, amendment := range amendments {
+		amendmentsByTx[amendment.TxId] = amendment
+	}
+
 	var history []*HistoryQueryResult
 	for resultsIterator.HasNext() {
 		response, err := resultsIterator.Next()
@@ -237,14 +595,12 @@ func (r *ResultContract) GetResultHistory(ctx contractapi.TransactionContextInte
 			return nil, fmt.Errorf("could not fetch result history: %v", err)
 		}
 
-		var result Result
+		result := &Result{ResultId: resultId}
 		if len(response.Value) > 0 {
-			err = json.Unmarshal(response.Value, &result)
+			result, err = decodeHistoricResult(response.Value)
 			if err != nil {
-				return nil, fmt.Errorf("could not unmarshal result history: %v", err)
+				return nil, err
 			}
-		} else {
-			result = Result{ResultId: resultId}
 		}
 
 		timestamp := response.Timestamp.AsTime()
@@ -253,8 +609,9 @@ func (r *ResultContract) GetResultHistory(ctx contractapi.TransactionContextInte
 		historyRecord := HistoryQueryResult{
 			TxId:      response.TxId,
 			Timestamp: formattedTime,
-			Record:    &result,
+			Record:    result,
 			IsDelete:  response.IsDelete,
+			Amendment: amendmentsByTx[response.TxId],
 		}
 		history = append(history, &historyRecord)
 	}
@@ -262,169 +619,79 @@ func (r *ResultContract) GetResultHistory(ctx contractapi.TransactionContextInte
 	return history, nil
 }
 
-// GetResultsWithPagination retrieves results with pagination
-func (r *ResultContract) GetResultsWithPagination(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*PaginatedQueryResult, error) {
-	queryString := `{"selector":{"assetType":"Result"}}`
-
-	resultsIterator, responseMetadata, err := ctx.GetStub().GetQueryResultWithPagination(queryString, pageSize, bookmark)
+// decodeHistoricResult decodes a past state of a result. States written before marks were typed
+// carry them as strings, which are converted like MigrateResults does; values that cannot be
+// parsed are left out of the record rather than failing the whole history.
+func decodeHistoricResult(value []byte) (*Result, error) {
+	var fields map[string]interface{}
+	err := json.Unmarshal(value, &fields)
 	if err != nil {
-		return nil, fmt.Errorf("could not fetch results with pagination: %v", err)
+		return nil, fmt.Errorf("could not unmarshal result history: %v", err)
 	}
-	defer resultsIterator.Close()
-
-	var results []*Result
-	for resultsIterator.HasNext() {
-		queryResult, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("could not fetch result: %s", err)
+	for _, field := range []string{"totalMarks", "obtainedMarks", "percentage"} {
+		if _, isString := fields[field].(string); !isString {
+			continue
 		}
-
-		var result Result
-		err = json.Unmarshal(queryResult.Value, &result)
+		number, err := parseLegacyNumber(fields[field])
 		if err != nil {
-			return nil, fmt.Errorf("could not unmarshal result: %v", err)
+			delete(fields, field)
+			continue
 		}
-
-		results = append(results, &result)
+		fields[field] = number
 	}
 
-	return &PaginatedQueryResult{
-		Records:             results,
-		FetchedRecordsCount: int32(len(results)),
-		Bookmark:            responseMetadata.Bookmark,
-	}, nil
-}
-
-func (r *ResultContract) GetMatchingResults(ctx contractapi.TransactionContextInterface, resultID string) ([]*Result, error) {
-	// Read the base result
-	baseResult, err := r.ReadResult(ctx, resultID)
+	resultBytes, err := json.Marshal(fields)
 	if err != nil {
-		return nil, fmt.Errorf("error reading result %v", err)
+		return nil, fmt.Errorf("failed to marshal result history: %v", err)
 	}
-
-	// Construct the query string to match the base result fields
-	queryString := fmt.Sprintf(
-		`{"selector":{"assetType":"Result","TotalMarks":"%s", "ObtainedMarks": "%s", "Percentage":"%s", "Status":"%s"}}`,
-		baseResult.TotalMarks, baseResult.ObtainedMarks, baseResult.Percentage, baseResult.Status,
-	)
-
-	// Execute the query in the collection
-	resultsIterator, err := ctx.GetStub().GetPrivateDataQueryResult(collectionName, queryString)
+	var result Result
+	err = json.Unmarshal(resultBytes, &result)
 	if err != nil {
-		return nil, fmt.Errorf("could not get the data: %v", err)
+		return nil, fmt.Errorf("could not unmarshal result history: %v", err)
 	}
-	defer resultsIterator.Close()
-
-	// Process the query results
-	return resultIteratorFunction(resultsIterator)
+	return &result, nil
 }
 
-// MatchResult matches result with a matching target result
-func (r *ResultContract) MatchResult(ctx contractapi.TransactionContextInterface, resultID string, targetResultID string) (string, error) {
-	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return "", fmt.Errorf("could not fetch client identity: %s", err)
-	}
-
-	// Ensure action is restricted to UniversityMSP
-	if clientOrgID != "UniversityMSP" {
-		return "", fmt.Errorf("user under following MSPID: %v can't perform this action", clientOrgID)
-	}
-
-	// Fetch target result from private data
-	bytes, err := ctx.GetStub().GetPrivateData(collectionName, targetResultID)
+// GetResultsWithPagination retrieves results with pagination
+func (r *ResultContract) GetResultsWithPagination(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*PaginatedQueryResult, error) {
+	_, err := authorize(ctx, ActionResultRead)
 	if err != nil {
-		return "", fmt.Errorf("could not fetch private data: %s", err)
+		return nil, err
 	}
 
-	var targetResult Result
-	err = json.Unmarshal(bytes, &targetResult)
-	if err != nil {
-		return "", fmt.Errorf("could not unmarshal target result data: %s", err)
-	}
+	queryString := `{"selector":{"assetType":"Result"}}`
 
-	// Read primary result
-	result, err := r.ReadResult(ctx, resultID)
+	resultsIterator, responseMetadata, err := ctx.GetStub().GetQueryResultWithPagination(queryString, pageSize, bookmark)
 	if err != nil {
-		return "", fmt.Errorf("could not read result data: %s", err)
+		return nil, fmt.Errorf("could not fetch results with pagination: %v", err)
 	}
+	defer resultsIterator.Close()
 
-	// Match results based on attributes
-	if result.TotalMarks == targetResult.TotalMarks && result.ObtainedMarks == targetResult.ObtainedMarks && result.Percentage == targetResult.Percentage {
-		result.Status = "Assigned"
-		result.StudentId = targetResult.StudentId
-
-		// Serialize the updated result
-		bytes, _ := json.Marshal(result)
-
-		// Delete the matched target result from private data
-		err = ctx.GetStub().DelPrivateData(collectionName, targetResultID)
+	var results []*Result
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
 		if err != nil {
-			return "", fmt.Errorf("could not delete target result: %s", err)
+			return nil, fmt.Errorf("could not fetch result: %s", err)
 		}
 
-		// Update the result state
-		err = ctx.GetStub().PutState(resultID, bytes)
+		var result Result
+		err = json.Unmarshal(queryResult.Value, &result)
 		if err != nil {
-			return "", fmt.Errorf("could not update result state: %s", err)
+			return nil, fmt.Errorf("could not unmarshal result: %v", err)
 		}
 
-		return fmt.Sprintf("Deleted target result %v and assigned result %v to student %v", targetResultID, resultID, targetResult.StudentId), nil
-	} else {
-		return "", fmt.Errorf("target result does not match")
-	}
-}
-
-// ConfirmResult confirms the student's result for a company
-func (r *ResultContract) ConfirmResult(ctx contractapi.TransactionContextInterface, resultID string, companyName string) (string, error) {
-	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return "", fmt.Errorf("could not get the MSPID: %s", err)
-	}
-
-	// Ensure only authorized organizations can perform this action
-	if clientOrgID != "UniversityMSP" {
-		return "", fmt.Errorf("user under following MSPID: %v cannot perform this action", clientOrgID)
-	}
-
-	// Read the result
-	result, err := r.ReadResult(ctx, resultID)
-	if err != nil {
-		return "", fmt.Errorf("could not read the result: %s", err)
+		results = append(results, &result)
 	}
 
-	// Update the result status and add company details
-	result.Status = fmt.Sprintf("Confirmed for %v", companyName)
-
-	bytes, _ := json.Marshal(result)
-
-	// Save the updated result
-	err = ctx.GetStub().PutState(resultID, bytes)
+	// Records the caller may not read are dropped from the page
+	results, err = readableResultsWithMarks(ctx, results)
 	if err != nil {
-		return "", fmt.Errorf("could not update result state: %s", err)
+		return nil, err
 	}
 
-	return fmt.Sprintf("Result %v successfully confirmed for %v", resultID, companyName), nil
-}
-
-// AddStudentResult stores the student's result in the blockchain
-func (r *ResultContract) AddStudentResult(ctx contractapi.TransactionContextInterface, studentId string, percentage string, status string) error {
-    studentResult := Result{
-        StudentId: studentId,
-        Percentage: percentage,
-        Status: status,
-    }
-
-    // Convert the studentResult to JSON
-    studentResultJSON, err := json.Marshal(studentResult)
-    if err != nil {
-        return fmt.Errorf("failed to marshal student result: %v", err)
-    }
-
-    // Store the student result in the ledger
-    return ctx.GetStub().PutState(studentId, studentResultJSON)
+	return &PaginatedQueryResult{
+		Records:             results,
+		FetchedRecordsCount: int32(len(results)),
+		Bookmark:            responseMetadata.Bookmark,
+	}, nil
 }
-
-
-
-
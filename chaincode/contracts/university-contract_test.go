@@ -0,0 +1,23 @@
+package contracts
+
+import (
+	"testing"
+)
+
+func TestDecodeHistoricResultAcceptsStringMarks(t *testing.T) {
+	result, err := decodeHistoricResult([]byte(`{"assetType":"Result","resultId":"RES1","studentId":"Stu1","totalMarks":"100","obtainedMarks":" 90 ","percentage":"90%","status":"Pass"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalMarks != 100 || result.ObtainedMarks != 90 || result.Percentage != 90 || result.Status != "Pass" {
+		t.Fatalf("unexpected legacy result %+v", result)
+	}
+
+	result, err = decodeHistoricResult([]byte(`{"resultId":"RES2","totalMarks":"n/a","obtainedMarks":45}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalMarks != 0 || result.ObtainedMarks != 45 {
+		t.Fatalf("expected unparseable marks to be left out, got %+v", result)
+	}
+}
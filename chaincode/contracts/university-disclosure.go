@@ -0,0 +1,354 @@
+package contracts
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Every institution keeps the clear values and salts behind its result commitments in its own
+// private collection, named after its MSP ID, of which it is the only member
+const disclosureCollectionPrefix string = "disclosures_"
+
+// Object type of the composite key (resultId) for public result commitments
+const commitmentObjectType string = "commitment"
+
+// Transient key carrying the issuer's secret from which per-field salts are derived
+const disclosureSecretKey string = "disclosureSecret"
+
+// Percentage bands committed as "percentageAtLeast<N>" claims, so a student can prove
+// a minimum percentage without revealing the exact figure
+var disclosureBands = []int{40, 50, 60, 70, 75, 80, 90}
+
+// FieldCommitment is the salted hash of one result field published on the ledger
+type FieldCommitment struct {
+	Field      string `json:"field"`      // Field or claim name, e.g. "status" or "percentageAtLeast60"
+	Commitment string `json:"commitment"` // Hex SHA-256 of field, value and salt
+}
+
+// ResultCommitment lists the public commitments of a result's fields
+type ResultCommitment struct {
+	AssetType     string             `json:"assetType"`     // Asset type ("ResultCommitment")
+	ResultId      string             `json:"resultId"`      // Committed result
+	ResultVersion int                `json:"resultVersion"` // Version of the result the commitments were computed from
+	IssuerMSP     string             `json:"issuerMsp"`     // Institution that published the commitments
+	CommittedAt   string             `json:"committedAt"`   // Transaction timestamp (RFC3339)
+	Fields        []*FieldCommitment `json:"fields"`        // One commitment per field or claim
+}
+
+// DisclosureItem reveals the value and salt behind one committed field
+type DisclosureItem struct {
+	Field string `json:"field"` // Field or claim name
+	Value string `json:"value"` // Clear value of the field
+	Salt  string `json:"salt"`  // Hex salt used in the commitment
+}
+
+// DisclosureBundle is what a student hands to a verifier: the fields they chose to reveal
+type DisclosureBundle struct {
+	ResultId string            `json:"resultId"` // Result the fields belong to
+	Items    []*DisclosureItem `json:"items"`    // Revealed fields
+}
+
+// FieldCheck is the verification outcome of one disclosed field
+type FieldCheck struct {
+	Field string `json:"field"` // Field or claim name
+	Value string `json:"value"` // Disclosed value
+	Valid bool   `json:"valid"` // Whether value and salt match the public commitment
+}
+
+// DisclosureVerification is the answer to VerifyDisclosure
+type DisclosureVerification struct {
+	ResultId  string        `json:"resultId"`  // Result the bundle refers to
+	IssuerMSP string        `json:"issuerMsp"` // Institution that issued the commitments
+	Revoked   bool          `json:"revoked"`   // Whether the result has since been revoked
+	Stale     bool          `json:"stale"`     // Whether the result has been amended since the commitments were published
+	Valid     bool          `json:"valid"`     // All fields match and the result is neither revoked nor amended
+	Fields    []*FieldCheck `json:"fields"`    // Per-field outcome
+}
+
+// CommitResultFields publishes salted commitments of a result's fields and stores the clear
+// values with their salts in the issuer's private collection. The transient field
+// "disclosureSecret" seeds the salts so that every endorser derives the same ones. Committing
+// again after an amendment publishes commitments of the current version.
+func (r *ResultContract) CommitResultFields(ctx contractapi.TransactionContextInterface, resultId string) (string, error) {
+	_, err := authorize(ctx, ActionDisclosureCommit)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := getResult(ctx, resultId)
+	if err != nil {
+		return "", err
+	}
+	if result.Revoked {
+		return "", newChaincodeError(CodeConflict, "result %s has been revoked and cannot be committed", resultId)
+	}
+	err = authorizeIssuer(ctx, result)
+	if err != nil {
+		return "", err
+	}
+	err = loadResultMarks(ctx, result)
+	if err != nil {
+		return "", err
+	}
+
+	transientData, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch transient data. %s", err)
+	}
+	secret, exists := transientData[disclosureSecretKey]
+	if !exists || len(secret) < 32 {
+		return "", newChaincodeError(CodeInvalidArgument, "the %s transient field must carry at least 32 random bytes", disclosureSecretKey)
+	}
+
+	committedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	commitment := ResultCommitment{
+		AssetType:     "ResultCommitment",
+		ResultId:      resultId,
+		ResultVersion: result.Version,
+		IssuerMSP:     result.IssuerMSP,
+		CommittedAt:   committedAt,
+	}
+	bundle := DisclosureBundle{ResultId: resultId}
+
+	for _, field := range disclosableFields(result) {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(resultId + "|" + field.Field))
+		field.Salt = hex.EncodeToString(mac.Sum(nil))
+
+		bundle.Items = append(bundle.Items, field)
+		commitment.Fields = append(commitment.Fields, &FieldCommitment{
+			Field:      field.Field,
+			Commitment: commitField(field.Field, field.Value, field.Salt),
+		})
+	}
+
+	bundleBytes, err := json.Marshal(bundle)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal disclosure values: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(disclosureCollectionName(result.IssuerMSP), resultId, bundleBytes)
+	if err != nil {
+		return "", fmt.Errorf("could not write disclosure values to the private collection: %v", err)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(commitmentObjectType, []string{resultId})
+	if err != nil {
+		return "", fmt.Errorf("could not create commitment key: %v", err)
+	}
+	commitmentBytes, err := json.Marshal(commitment)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal commitment: %v", err)
+	}
+	err = ctx.GetStub().PutState(key, commitmentBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to store commitment in world state: %v", err)
+	}
+
+	return fmt.Sprintf("Committed %d fields of result %v", len(commitment.Fields), resultId), nil
+}
+
+// ReadResultCommitment retrieves the public commitments of a result
+func (r *ResultContract) ReadResultCommitment(ctx contractapi.TransactionContextInterface, resultId string) (*ResultCommitment, error) {
+	_, err := authorize(ctx, ActionDisclosureVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	return getResultCommitment(ctx, resultId)
+}
+
+// GetDisclosureBundle returns the chosen fields of a result with their salts, for the student
+// who owns the result or registrars. The caller passes the bundle on to a verifier.
+func (r *ResultContract) GetDisclosureBundle(ctx contractapi.TransactionContextInterface, resultId string, fields []string) (*DisclosureBundle, error) {
+	result, err := getResult(ctx, resultId)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := authorize(ctx, ActionResultRead)
+	if err != nil {
+		return nil, err
+	}
+	switch role {
+	case RoleStudent:
+		studentId, err := clientStudentId(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if studentId != result.StudentId {
+			return nil, newChaincodeError(CodeForbidden, "student %s cannot disclose result %s", studentId, resultId)
+		}
+	case RoleRegistrar:
+		err = authorizeIssuer(ctx, result)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, newChaincodeError(CodeForbidden, "role %q cannot obtain disclosure values", role)
+	}
+
+	bundleBytes, err := ctx.GetStub().GetPrivateData(disclosureCollectionName(result.IssuerMSP), resultId)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the private data. %s", err)
+	}
+	if bundleBytes == nil {
+		return nil, newChaincodeError(CodeNotFound, "result %s has no current committed fields on this peer", resultId)
+	}
+
+	var stored DisclosureBundle
+	err = json.Unmarshal(bundleBytes, &stored)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal disclosure values: %v", err)
+	}
+
+	// Only the requested fields leave the collection
+	requested := make(map[string]bool)
+	for _, field := range fields {
+		requested[field] = true
+	}
+	bundle := &DisclosureBundle{ResultId: resultId, Items: []*DisclosureItem{}}
+	for _, item := range stored.Items {
+		if requested[item.Field] {
+			bundle.Items = append(bundle.Items, item)
+		}
+	}
+	if len(bundle.Items) != len(requested) {
+		return nil, newChaincodeError(CodeInvalidArgument, "some requested fields were not committed for result %s", resultId)
+	}
+
+	return bundle, nil
+}
+
+// VerifyDisclosure checks a disclosure bundle (JSON with resultId and items of field, value
+// and salt) against the public commitments of the result. Commitments of a revoked result, or
+// of a version the issuer has since amended, no longer verify.
+func (r *ResultContract) VerifyDisclosure(ctx contractapi.TransactionContextInterface, bundleJSON string) (*DisclosureVerification, error) {
+	_, err := authorize(ctx, ActionDisclosureVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle DisclosureBundle
+	err = json.Unmarshal([]byte(bundleJSON), &bundle)
+	if err != nil {
+		return nil, newChaincodeError(CodeInvalidArgument, "could not parse disclosure bundle: %v", err)
+	}
+	if len(bundle.Items) == 0 {
+		return nil, newChaincodeError(CodeInvalidArgument, "the disclosure bundle does not reveal any field")
+	}
+
+	commitment, err := getResultCommitment(ctx, bundle.ResultId)
+	if err != nil {
+		return nil, err
+	}
+	commitments := make(map[string]string)
+	for _, field := range commitment.Fields {
+		commitments[field.Field] = field.Commitment
+	}
+
+	verification := &DisclosureVerification{
+		ResultId:  bundle.ResultId,
+		IssuerMSP: commitment.IssuerMSP,
+		Valid:     true,
+	}
+
+	// A revoked or amended result invalidates every disclosure made from it
+	result, err := getResult(ctx, bundle.ResultId)
+	switch {
+	case err != nil || result.Revoked:
+		verification.Revoked = true
+		verification.Valid = false
+	case result.Version != commitment.ResultVersion:
+		verification.Stale = true
+		verification.Valid = false
+	}
+
+	for _, item := range bundle.Items {
+		expected, ok := commitments[item.Field]
+		valid := ok && hmac.Equal([]byte(expected), []byte(commitField(item.Field, item.Value, item.Salt)))
+		if !valid {
+			verification.Valid = false
+		}
+		verification.Fields = append(verification.Fields, &FieldCheck{Field: item.Field, Value: item.Value, Valid: valid})
+	}
+
+	return verification, nil
+}
+
+// invalidateDisclosure removes the disclosure values of an amended result, so that students cannot
+// obtain bundles of the previous version. Its public commitments stay to report stale disclosures.
+func invalidateDisclosure(ctx contractapi.TransactionContextInterface, result *Result) error {
+	if result.IssuerMSP == "" {
+		return nil
+	}
+	err := ctx.GetStub().DelPrivateData(disclosureCollectionName(result.IssuerMSP), result.ResultId)
+	if err != nil {
+		return fmt.Errorf("could not remove disclosure values of result %s: %v", result.ResultId, err)
+	}
+	return nil
+}
+
+// disclosureCollectionName returns the private collection holding the disclosure values of an
+// institution
+func disclosureCollectionName(issuerMSP string) string {
+	return disclosureCollectionPrefix + issuerMSP
+}
+
+// disclosableFields lists the committed fields and percentage band claims of a result, without salts
+func disclosableFields(result *Result) []*DisclosureItem {
+	items := []*DisclosureItem{
+		{Field: "studentId", Value: result.StudentId},
+		{Field: "issuerMsp", Value: result.IssuerMSP},
+		{Field: "totalMarks", Value: formatNumber(result.TotalMarks)},
+		{Field: "obtainedMarks", Value: formatNumber(result.ObtainedMarks)},
+		{Field: "percentage", Value: formatNumber(result.Percentage)},
+		{Field: "status", Value: result.Status},
+	}
+	for _, band := range disclosureBands {
+		items = append(items, &DisclosureItem{
+			Field: fmt.Sprintf("percentageAtLeast%d", band),
+			Value: fmt.Sprintf("%t", result.Percentage >= float64(band)),
+		})
+	}
+	return items
+}
+
+// commitField computes the hex SHA-256 commitment of a field value under a salt
+func commitField(field string, value string, salt string) string {
+	digest := sha256.Sum256([]byte(strings.Join([]string{field, value, salt}, "|")))
+	return hex.EncodeToString(digest[:])
+}
+
+// getResultCommitment reads the public commitments of a result
+func getResultCommitment(ctx contractapi.TransactionContextInterface, resultId string) (*ResultCommitment, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(commitmentObjectType, []string{resultId})
+	if err != nil {
+		return nil, fmt.Errorf("could not create commitment key: %v", err)
+	}
+
+	commitmentBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if commitmentBytes == nil {
+		return nil, newChaincodeError(CodeNotFound, "result %s has no published commitments", resultId)
+	}
+
+	var commitment ResultCommitment
+	err = json.Unmarshal(commitmentBytes, &commitment)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal commitment: %v", err)
+	}
+
+	return &commitment, nil
+}
@@ -0,0 +1,184 @@
+package contracts
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Object type of the composite key (sha256) for anchored documents
+const documentObjectType string = "document"
+
+// DocumentAnchor ties the SHA-256 of an issued document such as a mark sheet to a result
+type DocumentAnchor struct {
+	AssetType  string `json:"assetType"`  // Asset type ("DocumentAnchor")
+	Hash       string `json:"hash"`       // Lowercase hex SHA-256 of the document bytes
+	MimeType   string `json:"mimeType"`   // MIME type of the document, e.g. "application/pdf"
+	ResultId   string `json:"resultId"`   // Result the document certifies
+	IssuerMSP  string `json:"issuerMsp"`  // Institution that anchored the document
+	AnchoredBy string `json:"anchoredBy"` // Registrar identity that anchored the document
+	AnchoredAt string `json:"anchoredAt"` // Transaction timestamp (RFC3339)
+}
+
+// DocumentVerification is the answer to VerifyDocument
+type DocumentVerification struct {
+	Hash   string          `json:"hash"`                                  // Hash that was looked up
+	Valid  bool            `json:"valid"`                                 // Whether the anchor and its result are still in force
+	Reason string          `json:"reason"`                                // Why the anchor is or is not valid
+	Anchor *DocumentAnchor `json:"anchor,omitempty" metadata:",optional"` // Anchor record, when the hash is known
+	Result *Result         `json:"result,omitempty" metadata:",optional"` // Linked result, when it still exists
+}
+
+// AnchorDocument records the SHA-256 of a document issued for a result
+func (r *ResultContract) AnchorDocument(ctx contractapi.TransactionContextInterface, resultId string, sha256Hex string, mimeType string) (string, error) {
+	hash, err := normalizeDocumentHash(sha256Hex)
+	if err != nil {
+		return "", err
+	}
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return "", newChaincodeError(CodeInvalidArgument, "invalid MIME type %q: %v", mimeType, err)
+	}
+
+	_, err = authorize(ctx, ActionDocumentAnchor)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := getResult(ctx, resultId)
+	if err != nil {
+		return "", err
+	}
+	if result.Revoked {
+		return "", newChaincodeError(CodeConflict, "result %s has been revoked and cannot have documents anchored", resultId)
+	}
+	err = authorizeIssuer(ctx, result)
+	if err != nil {
+		return "", err
+	}
+
+	existing, err := getDocumentAnchor(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return "", newChaincodeError(CodeConflict, "document %s is already anchored to result %s", hash, existing.ResultId)
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	anchoredAt, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	anchor := DocumentAnchor{
+		AssetType:  "DocumentAnchor",
+		Hash:       hash,
+		MimeType:   mediaType,
+		ResultId:   resultId,
+		IssuerMSP:  result.IssuerMSP,
+		AnchoredBy: clientID,
+		AnchoredAt: anchoredAt,
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(documentObjectType, []string{hash})
+	if err != nil {
+		return "", fmt.Errorf("could not create document key: %v", err)
+	}
+	anchorBytes, err := json.Marshal(anchor)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal document anchor: %v", err)
+	}
+	err = ctx.GetStub().PutState(key, anchorBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to store document anchor in world state: %v", err)
+	}
+
+	return fmt.Sprintf("Document %v anchored to result %v", hash, resultId), nil
+}
+
+// VerifyDocument looks up a document hash and reports the linked result and whether it is still valid
+func (r *ResultContract) VerifyDocument(ctx contractapi.TransactionContextInterface, sha256Hex string) (*DocumentVerification, error) {
+	hash, err := normalizeDocumentHash(sha256Hex)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = authorize(ctx, ActionDocumentVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &DocumentVerification{Hash: hash}
+
+	anchor, err := getDocumentAnchor(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if anchor == nil {
+		verification.Reason = "document was never anchored on the ledger"
+		return verification, nil
+	}
+	verification.Anchor = anchor
+
+	// Whoever holds the document already holds the marks it certifies
+	result, err := getResult(ctx, anchor.ResultId)
+	if err != nil {
+		verification.Reason = fmt.Sprintf("linked result %s no longer exists", anchor.ResultId)
+		return verification, nil
+	}
+	verification.Result = result
+
+	if result.Revoked {
+		verification.Reason = fmt.Sprintf("linked result %s has been revoked", result.ResultId)
+		if result.Revocation != nil && result.Revocation.ReplacedBy != "" {
+			verification.Reason += fmt.Sprintf(" and replaced by %s", result.Revocation.ReplacedBy)
+		}
+		return verification, nil
+	}
+
+	verification.Valid = true
+	verification.Reason = fmt.Sprintf("document matches result %s issued by %s", result.ResultId, result.IssuerMSP)
+	return verification, nil
+}
+
+// getDocumentAnchor reads an anchor, returning nil when the hash is unknown
+func getDocumentAnchor(ctx contractapi.TransactionContextInterface, hash string) (*DocumentAnchor, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(documentObjectType, []string{hash})
+	if err != nil {
+		return nil, fmt.Errorf("could not create document key: %v", err)
+	}
+
+	anchorBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if anchorBytes == nil {
+		return nil, nil
+	}
+
+	var anchor DocumentAnchor
+	err = json.Unmarshal(anchorBytes, &anchor)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal document anchor: %v", err)
+	}
+
+	return &anchor, nil
+}
+
+// normalizeDocumentHash checks for a hex encoded SHA-256 digest and lowercases it
+func normalizeDocumentHash(sha256Hex string) (string, error) {
+	hash := strings.ToLower(strings.TrimSpace(sha256Hex))
+	decoded, err := hex.DecodeString(hash)
+	if err != nil || len(decoded) != 32 {
+		return "", newChaincodeError(CodeInvalidArgument, "%q is not a hex encoded SHA-256 digest", sha256Hex)
+	}
+	return hash, nil
+}
@@ -0,0 +1,175 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Revocation records why, when and by whom a result was withdrawn
+type Revocation struct {
+	Reason       string `json:"reason"`                                    // Reason given for the revocation
+	RevokedAt    string `json:"revokedAt"`                                 // Transaction timestamp of the revocation (RFC3339)
+	RevokedBy    string `json:"revokedBy"`                                 // Client identity that revoked the result
+	RevokedByMSP string `json:"revokedByMsp"`                              // MSP of the revoking identity
+	ReplacedBy   string `json:"replacedBy,omitempty" metadata:",optional"` // ID of the result reissued in its place
+}
+
+// RevocationEvent is the payload of the ResultRevoked chaincode event
+type RevocationEvent struct {
+	ResultId   string `json:"resultId"`
+	StudentId  string `json:"studentId"`
+	Reason     string `json:"reason"`
+	RevokedAt  string `json:"revokedAt"`
+	ReplacedBy string `json:"replacedBy,omitempty"`
+}
+
+// RevokeResult withdraws a result while keeping it readable on the ledger
+func (r *ResultContract) RevokeResult(ctx contractapi.TransactionContextInterface, resultId string, reason string) (string, error) {
+	if strings.TrimSpace(reason) == "" {
+		return "", newChaincodeError(CodeInvalidArgument, "a reason is required to revoke a result")
+	}
+
+	result, err := r.revokeResult(ctx, resultId, reason, "")
+	if err != nil {
+		return "", err
+	}
+
+	err = emitRevocationEvent(ctx, result)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Successfully revoked result %v", resultId), nil
+}
+
+// ReissueResult revokes an existing result and issues a replacement for the same student
+func (r *ResultContract) ReissueResult(ctx contractapi.TransactionContextInterface, oldResultId string, newResultId string, totalMarks float64, obtainedMarks float64, reason string) (string, error) {
+	if strings.TrimSpace(newResultId) == "" {
+		return "", newChaincodeError(CodeInvalidArgument, "newResultId cannot be empty")
+	}
+	if oldResultId == newResultId {
+		return "", newChaincodeError(CodeInvalidArgument, "the reissued result must have a new ID")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return "", newChaincodeError(CodeInvalidArgument, "a reason is required to reissue a result")
+	}
+	if err := validateMarks(totalMarks, obtainedMarks); err != nil {
+		return "", err
+	}
+
+	oldResult, err := r.revokeResult(ctx, oldResultId, reason, newResultId)
+	if err != nil {
+		return "", err
+	}
+
+	newResult, err := r.newResult(ctx, newResultId, oldResult.StudentId, totalMarks, obtainedMarks)
+	if err != nil {
+		return "", err
+	}
+	newResult.ReplacesResultId = oldResultId
+
+	err = putResult(ctx, newResult)
+	if err != nil {
+		return "", err
+	}
+
+	err = emitRevocationEvent(ctx, oldResult)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Successfully revoked result %v and reissued it as %v", oldResultId, newResultId), nil
+}
+
+// revokeResult marks a result as revoked by the invoking registrar and stores it
+func (r *ResultContract) revokeResult(ctx contractapi.TransactionContextInterface, resultId string, reason string, replacedBy string) (*Result, error) {
+	_, err := authorize(ctx, ActionResultRevoke)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+
+	result, err := r.ReadResult(ctx, resultId)
+	if err != nil {
+		return nil, err
+	}
+	if result.Revoked {
+		return nil, newChaincodeError(CodeConflict, "result %s is already revoked", resultId)
+	}
+	err = authorizeIssuer(ctx, result)
+	if err != nil {
+		return nil, err
+	}
+
+	revokedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Revoked = true
+	result.Revocation = &Revocation{
+		Reason:       reason,
+		RevokedAt:    revokedAt,
+		RevokedBy:    clientID,
+		RevokedByMSP: clientOrgID,
+		ReplacedBy:   replacedBy,
+	}
+
+	err = putResult(ctx, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// emitRevocationEvent sets the ResultRevoked chaincode event for a revoked result
+func emitRevocationEvent(ctx contractapi.TransactionContextInterface, result *Result) error {
+	eventBytes, err := json.Marshal(RevocationEvent{
+		ResultId:   result.ResultId,
+		StudentId:  result.StudentId,
+		Reason:     result.Revocation.Reason,
+		RevokedAt:  result.Revocation.RevokedAt,
+		ReplacedBy: result.Revocation.ReplacedBy,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal revocation event: %v", err)
+	}
+
+	err = ctx.GetStub().SetEvent("ResultRevoked", eventBytes)
+	if err != nil {
+		return fmt.Errorf("failed to set revocation event: %v", err)
+	}
+	return nil
+}
+
+// txTimestamp returns the transaction timestamp formatted as RFC3339
+func txTimestamp(ctx contractapi.TransactionContextInterface) (string, error) {
+	timestamp, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	return timestamp.Format(time.RFC3339), nil
+}
+
+// txTime returns the transaction timestamp as a UTC time
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not read transaction timestamp: %v", err)
+	}
+	return timestamp.AsTime().UTC(), nil
+}
@@ -0,0 +1,490 @@
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Object type of the composite key (studentId, term, courseCode) for course grades
+const courseGradeObjectType string = "courseGrade"
+
+// Highest grade point a course can be awarded
+const maxGradePoints float64 = 10
+
+// CourseGrade represents a student's grade in a single course for a term. Grade points and letter
+// grade are kept in the marks collection of the issuer, like the marks of results.
+type CourseGrade struct {
+	AssetType     string  `json:"assetType"`                                    // Asset type ("CourseGrade")
+	StudentId     string  `json:"studentId"`                                    // Identifier for the student
+	IssuerMSP     string  `json:"issuerMsp"`                                    // MSP ID of the institution that recorded the grade
+	Term          string  `json:"term"`                                         // Academic term, e.g. "2024-SEM1"
+	CourseCode    string  `json:"courseCode"`                                   // Course code, e.g. "CS101"
+	CourseName    string  `json:"courseName"`                                   // Human readable course name
+	Credits       float64 `json:"credits"`                                      // Credits carried by the course
+	GradePoints   float64 `json:"gradePoints,omitempty" metadata:",optional"`   // Grade points awarded, 0 to maxGradePoints
+	Grade         string  `json:"grade,omitempty" metadata:",optional"`         // Letter grade, e.g. "A"
+	ReasonCode    string  `json:"reasonCode,omitempty" metadata:",optional"`    // Reason code of the last correction
+	Justification string  `json:"justification,omitempty" metadata:",optional"` // Justification of the last correction
+	CorrectedAt   string  `json:"correctedAt,omitempty" metadata:",optional"`   // Transaction timestamp of the last correction (RFC3339)
+	salt          string
+}
+
+// gradeValues is the private part of a course grade
+type gradeValues struct {
+	GradePoints float64 `json:"gradePoints"`
+	Grade       string  `json:"grade"`
+	Salt        string  `json:"salt"`
+}
+
+// TermSummary groups the course grades of one term with its computed SGPA
+type TermSummary struct {
+	Term    string         `json:"term"`    // Academic term
+	Courses []*CourseGrade `json:"courses"` // Course grades recorded for the term
+	Credits float64        `json:"credits"` // Credits attempted in the term
+	SGPA    float64        `json:"sgpa"`    // Semester grade point average
+}
+
+// Transcript is the course-by-course record of a student with computed CGPA
+type Transcript struct {
+	StudentId    string         `json:"studentId"`    // Identifier for the student
+	Terms        []*TermSummary `json:"terms"`        // Terms ordered by term identifier
+	TotalCredits float64        `json:"totalCredits"` // Credits attempted across all terms
+	CGPA         float64        `json:"cgpa"`         // Cumulative grade point average
+}
+
+// PaginatedCourseGradeResult supports paginated queries of course grades
+type PaginatedCourseGradeResult struct {
+	Records             []*CourseGrade `json:"records"`             // List of course grade records
+	FetchedRecordsCount int32          `json:"fetchedRecordsCount"` // Number of records fetched
+	Bookmark            string         `json:"bookmark"`            // Bookmark for pagination
+}
+
+// AddCourseGrade records a student's grade for a course in a term. The grade points and letter
+// grade are stored in the issuer's marks collection, salted from the "resultSalt" transient field.
+func (r *ResultContract) AddCourseGrade(ctx contractapi.TransactionContextInterface, studentId string, term string, courseCode string, courseName string, credits float64, gradePoints float64, grade string) (string, error) {
+	// Validate input parameters
+	if strings.TrimSpace(studentId) == "" || strings.TrimSpace(term) == "" || strings.TrimSpace(courseCode) == "" {
+		return "", newChaincodeError(CodeInvalidArgument, "studentId, term and courseCode cannot be empty")
+	}
+	if math.IsNaN(credits) || math.IsInf(credits, 0) || credits <= 0 {
+		return "", newChaincodeError(CodeInvalidArgument, "credits must be a positive number, got %v", credits)
+	}
+	if err := validateGradePoints(gradePoints); err != nil {
+		return "", err
+	}
+
+	// Verify client organization identity
+	_, err := authorize(ctx, ActionTranscriptWrite)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(courseGradeObjectType, []string{studentId, term, courseCode})
+	if err != nil {
+		return "", fmt.Errorf("could not create course grade key: %v", err)
+	}
+
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return "", newChaincodeError(CodeConflict, "grade for course %s in term %s already exists for student %s", courseCode, term, studentId)
+	}
+
+	issuerMSP, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+
+	courseGrade := CourseGrade{
+		AssetType:   "CourseGrade",
+		StudentId:   studentId,
+		IssuerMSP:   issuerMSP,
+		Term:        term,
+		CourseCode:  courseCode,
+		CourseName:  courseName,
+		Credits:     credits,
+		GradePoints: gradePoints,
+		Grade:       grade,
+	}
+	courseGrade.salt, err = resultSalt(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	err = putCourseGrade(ctx, key, &courseGrade)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Successfully added grade for course %v in term %v for student %v", courseCode, term, studentId), nil
+}
+
+// CorrectCourseGrade changes the grade points and letter grade of a recorded course grade with a
+// reason code and justification. Only the institution that recorded the grade may correct it.
+func (r *ResultContract) CorrectCourseGrade(ctx contractapi.TransactionContextInterface, studentId string, term string, courseCode string, gradePoints float64, grade string, reasonCode string, justification string) (string, error) {
+	if !amendmentReasonCodes[reasonCode] {
+		return "", newChaincodeError(CodeInvalidArgument, "unknown reason code %q", reasonCode)
+	}
+	if strings.TrimSpace(justification) == "" {
+		return "", newChaincodeError(CodeInvalidArgument, "a justification is required to correct a grade")
+	}
+	if err := validateGradePoints(gradePoints); err != nil {
+		return "", err
+	}
+
+	_, err := authorize(ctx, ActionTranscriptWrite)
+	if err != nil {
+		return "", err
+	}
+
+	key, courseGrade, err := getCourseGrade(ctx, studentId, term, courseCode)
+	if err != nil {
+		return "", err
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	if courseGrade.IssuerMSP != clientOrgID {
+		return "", newChaincodeError(CodeForbidden, "grade for course %s in term %s was recorded by %q and cannot be changed by %v", courseCode, term, courseGrade.IssuerMSP, clientOrgID)
+	}
+
+	correctedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	courseGrade.GradePoints = gradePoints
+	courseGrade.Grade = grade
+	courseGrade.ReasonCode = reasonCode
+	courseGrade.Justification = justification
+	courseGrade.CorrectedAt = correctedAt
+	courseGrade.salt, err = resultSalt(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	err = putCourseGrade(ctx, key, courseGrade)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Successfully corrected grade for course %v in term %v for student %v", courseCode, term, studentId), nil
+}
+
+// ReadCourseGrade retrieves a single course grade from the world state
+func (r *ResultContract) ReadCourseGrade(ctx contractapi.TransactionContextInterface, studentId string, term string, courseCode string) (*CourseGrade, error) {
+	err := authorizeTranscriptRead(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, courseGrade, err := getCourseGrade(ctx, studentId, term, courseCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return courseGrade, nil
+}
+
+// GetCourseGradesByTerm retrieves all course grades of a student for one term
+func (r *ResultContract) GetCourseGradesByTerm(ctx contractapi.TransactionContextInterface, studentId string, term string) ([]*CourseGrade, error) {
+	err := authorizeTranscriptRead(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	gradesIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(courseGradeObjectType, []string{studentId, term})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch course grades: %v", err)
+	}
+	defer gradesIterator.Close()
+
+	return courseGradeIteratorFunction(ctx, gradesIterator)
+}
+
+// GetTranscript builds a student's transcript with per-term SGPA and overall CGPA
+func (r *ResultContract) GetTranscript(ctx contractapi.TransactionContextInterface, studentId string) (*Transcript, error) {
+	if strings.TrimSpace(studentId) == "" {
+		return nil, newChaincodeError(CodeInvalidArgument, "studentId cannot be empty")
+	}
+	err := authorizeTranscriptRead(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	grades, err := getCourseGradesByStudent(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+	if len(grades) == 0 {
+		return nil, newChaincodeError(CodeNotFound, "no course grades recorded for student %s", studentId)
+	}
+
+	return buildTranscript(studentId, grades), nil
+}
+
+// GetCourseGradesWithPagination retrieves course grades with pagination, optionally for a single student
+func (r *ResultContract) GetCourseGradesWithPagination(ctx contractapi.TransactionContextInterface, studentId string, pageSize int32, bookmark string) (*PaginatedCourseGradeResult, error) {
+	err := authorizeTranscriptRead(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	var attributes []string
+	if studentId != "" {
+		attributes = []string{studentId}
+	}
+
+	gradesIterator, responseMetadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(courseGradeObjectType, attributes, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch course grades with pagination: %v", err)
+	}
+	defer gradesIterator.Close()
+
+	grades, err := courseGradeIteratorFunction(ctx, gradesIterator)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaginatedCourseGradeResult{
+		Records:             grades,
+		FetchedRecordsCount: int32(len(grades)),
+		Bookmark:            responseMetadata.Bookmark,
+	}, nil
+}
+
+// authorizeTranscriptRead lets university staff read any grades and students only their own. Companies
+// need an active consent grant covering TranscriptConsent, and each of their reads is audited, so
+// they must submit rather than evaluate. An empty studentId, meaning all students, is staff-only.
+func authorizeTranscriptRead(ctx contractapi.TransactionContextInterface, studentId string) error {
+	role, err := authorize(ctx, ActionTranscriptRead)
+	if err != nil {
+		return err
+	}
+
+	if studentId == "" && (role == RoleStudent || role == RoleHR) {
+		return newChaincodeError(CodeInvalidArgument, "studentId cannot be empty")
+	}
+
+	switch role {
+	case RoleStudent:
+		callerStudentId, err := clientStudentId(ctx)
+		if err != nil {
+			return err
+		}
+		if callerStudentId != studentId {
+			return newChaincodeError(CodeForbidden, "student %s cannot read grades of student %q", callerStudentId, studentId)
+		}
+	case RoleHR:
+		clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+		if err != nil {
+			return fmt.Errorf("could not fetch client identity: %s", err)
+		}
+		grant, err := findActiveGrant(ctx, clientOrgID, studentId, TranscriptConsent)
+		if err != nil {
+			return err
+		}
+		if grant == nil {
+			return newChaincodeError(CodeForbidden, "no active consent allows this identity to read grades of student %s", studentId)
+		}
+		return recordConsentEvent(ctx, studentId, ConsentAccessed, grant.GrantId, TranscriptConsent)
+	}
+	return nil
+}
+
+// readableTranscript reads the course grades of a student if authorizeTranscriptRead lets the caller
+// read them, recording the access of companies. It reports false instead of failing when the caller
+// has no consent, for checks that treat an unreadable transcript as an outcome.
+func readableTranscript(ctx contractapi.TransactionContextInterface, studentId string) ([]*CourseGrade, bool, error) {
+	err := authorizeTranscriptRead(ctx, studentId)
+	var coded *ChaincodeError
+	if errors.As(err, &coded) && coded.Code == CodeForbidden {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	grades, err := getCourseGradesByStudent(ctx, studentId)
+	if err != nil {
+		return nil, false, err
+	}
+	return grades, true, nil
+}
+
+// getCourseGradesByStudent reads every course grade of a student without applying read authorization
+func getCourseGradesByStudent(ctx contractapi.TransactionContextInterface, studentId string) ([]*CourseGrade, error) {
+	gradesIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(courseGradeObjectType, []string{studentId})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch course grades: %v", err)
+	}
+	defer gradesIterator.Close()
+
+	return courseGradeIteratorFunction(ctx, gradesIterator)
+}
+
+// getCourseGrade reads a course grade with its private values without applying read authorization
+func getCourseGrade(ctx contractapi.TransactionContextInterface, studentId string, term string, courseCode string) (string, *CourseGrade, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(courseGradeObjectType, []string{studentId, term, courseCode})
+	if err != nil {
+		return "", nil, fmt.Errorf("could not create course grade key: %v", err)
+	}
+
+	gradeBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if gradeBytes == nil {
+		return "", nil, newChaincodeError(CodeNotFound, "no grade for course %s in term %s for student %s", courseCode, term, studentId)
+	}
+
+	var courseGrade CourseGrade
+	err = json.Unmarshal(gradeBytes, &courseGrade)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not unmarshal course grade: %v", err)
+	}
+	err = loadGradeValues(ctx, key, &courseGrade)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return key, &courseGrade, nil
+}
+
+// putCourseGrade writes the public part of a course grade to the world state and its grade points
+// and letter grade to the marks collection of its issuer
+func putCourseGrade(ctx contractapi.TransactionContextInterface, key string, courseGrade *CourseGrade) error {
+	valuesBytes, err := json.Marshal(gradeValues{
+		GradePoints: courseGrade.GradePoints,
+		Grade:       courseGrade.Grade,
+		Salt:        courseGrade.salt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal grade values: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(marksCollectionName(courseGrade.IssuerMSP), key, valuesBytes)
+	if err != nil {
+		return fmt.Errorf("could not write grade values to the private collection: %v", err)
+	}
+
+	public := *courseGrade
+	public.GradePoints = 0
+	public.Grade = ""
+	gradeBytes, err := json.Marshal(public)
+	if err != nil {
+		return fmt.Errorf("failed to marshal course grade: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(key, gradeBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store course grade in world state: %v", err)
+	}
+	return nil
+}
+
+// loadGradeValues fills in the grade points and letter grade of a course grade from the marks
+// collection of its issuer. Grades recorded before their values became private carry them publicly.
+func loadGradeValues(ctx contractapi.TransactionContextInterface, key string, courseGrade *CourseGrade) error {
+	if courseGrade.IssuerMSP == "" {
+		return nil
+	}
+	collection := marksCollectionName(courseGrade.IssuerMSP)
+	valuesBytes, err := ctx.GetStub().GetPrivateData(collection, key)
+	if err != nil {
+		return fmt.Errorf("grades of student %s can only be read on peers of the %s collection: %v", courseGrade.StudentId, collection, err)
+	}
+	if valuesBytes == nil {
+		return nil
+	}
+
+	var values gradeValues
+	err = json.Unmarshal(valuesBytes, &values)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal grade values: %v", err)
+	}
+	courseGrade.GradePoints = values.GradePoints
+	courseGrade.Grade = values.Grade
+	courseGrade.salt = values.Salt
+	return nil
+}
+
+// validateGradePoints rejects grade points outside 0 to maxGradePoints
+func validateGradePoints(gradePoints float64) error {
+	if math.IsNaN(gradePoints) || gradePoints < 0 || gradePoints > maxGradePoints {
+		return newChaincodeError(CodeInvalidArgument, "gradePoints must be between 0 and %v, got %v", maxGradePoints, gradePoints)
+	}
+	return nil
+}
+
+func courseGradeIteratorFunction(ctx contractapi.TransactionContextInterface, gradesIterator shim.StateQueryIteratorInterface) ([]*CourseGrade, error) {
+	var grades []*CourseGrade
+	for gradesIterator.HasNext() {
+		queryResult, err := gradesIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch the details of the course grade iterator. %s", err)
+		}
+		var courseGrade CourseGrade
+		err = json.Unmarshal(queryResult.Value, &courseGrade)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal the data. %s", err)
+		}
+		err = loadGradeValues(ctx, queryResult.Key, &courseGrade)
+		if err != nil {
+			return nil, err
+		}
+		grades = append(grades, &courseGrade)
+	}
+
+	return grades, nil
+}
+
+// buildTranscript groups grades by term and computes credit-weighted averages
+func buildTranscript(studentId string, grades []*CourseGrade) *Transcript {
+	termsByName := make(map[string]*TermSummary)
+	var termNames []string
+	var totalCredits, totalWeighted float64
+
+	for _, grade := range grades {
+		summary, ok := termsByName[grade.Term]
+		if !ok {
+			summary = &TermSummary{Term: grade.Term}
+			termsByName[grade.Term] = summary
+			termNames = append(termNames, grade.Term)
+		}
+		summary.Courses = append(summary.Courses, grade)
+		summary.Credits += grade.Credits
+		summary.SGPA += grade.Credits * grade.GradePoints
+
+		totalCredits += grade.Credits
+		totalWeighted += grade.Credits * grade.GradePoints
+	}
+
+	sort.Strings(termNames)
+	transcript := &Transcript{StudentId: studentId, TotalCredits: totalCredits}
+	for _, name := range termNames {
+		summary := termsByName[name]
+		// SGPA holds the weighted sum until here
+		summary.SGPA = roundGradePoints(summary.SGPA / summary.Credits)
+		transcript.Terms = append(transcript.Terms, summary)
+	}
+	if totalCredits > 0 {
+		transcript.CGPA = roundGradePoints(totalWeighted / totalCredits)
+	}
+
+	return transcript
+}
+
+// roundGradePoints rounds a grade point average to two decimal places
+func roundGradePoints(value float64) float64 {
+	return math.Round(value*100) / 100
+}
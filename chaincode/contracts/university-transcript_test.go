@@ -0,0 +1,48 @@
+package contracts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCorrectCourseGradeRequiresIssuer(t *testing.T) {
+	stub := newRegistryStub(t)
+	registerInstitution(t, stub, "OtherUniversityMSP", InstitutionActive, "2025-01-01T00:00:00Z", "2027-01-01T00:00:00Z")
+	stub.defineCollections(marksCollectionName("OtherUniversityMSP"))
+	contract := new(ResultContract)
+
+	registrar := newMockContext(stub, "UniversityMSP", RoleRegistrar)
+	_, err := contract.AddCourseGrade(registrar, "Stu1", "2024-SEM1", "CS101", "Programming Fundamentals", 4, 9, "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Grade values are kept out of public state
+	key, _ := stub.CreateCompositeKey(courseGradeObjectType, []string{"Stu1", "2024-SEM1", "CS101"})
+	if strings.Contains(string(stub.state[key]), "gradePoints") {
+		t.Fatalf("expected grade points to be private, got %s", stub.state[key])
+	}
+
+	// Another institution can neither overwrite nor correct the grade
+	foreign := newMockContext(stub, "OtherUniversityMSP", RoleRegistrar)
+	_, err = contract.AddCourseGrade(foreign, "Stu1", "2024-SEM1", "CS101", "Programming Fundamentals", 4, 2, "F")
+	if err == nil {
+		t.Fatal("expected an existing grade not to be overwritten")
+	}
+	_, err = contract.CorrectCourseGrade(foreign, "Stu1", "2024-SEM1", "CS101", 2, "F", "ADMINISTRATIVE", "Keyed in by the wrong office")
+	if err == nil || !strings.Contains(err.Error(), CodeForbidden) {
+		t.Fatalf("expected a foreign institution to be forbidden, got %v", err)
+	}
+
+	_, err = contract.CorrectCourseGrade(registrar, "Stu1", "2024-SEM1", "CS101", 8, "B", "RE_EVALUATION", "Script re-evaluated on appeal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, grade, err := getCourseGrade(registrar, "Stu1", "2024-SEM1", "CS101")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grade.GradePoints != 8 || grade.Grade != "B" || grade.ReasonCode != "RE_EVALUATION" {
+		t.Fatalf("unexpected corrected grade %+v", grade)
+	}
+}
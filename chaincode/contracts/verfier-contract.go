@@ -3,7 +3,7 @@ package contracts
 import (
 	"encoding/json"
 	"fmt"
-	"strconv"
+	"time"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
@@ -15,212 +15,353 @@ type OfferContract struct {
 
 // Offer represents the structure of a job offer letter
 type Offer struct {
-	OfferId        string `json:"offerId"`        // Unique identifier for the offer
-	AssetType      string `json:"assetType"`      // Type of asset (e.g., "OfferLetter")
-	Ctc            string `json:"ctc"`            // Cost to Company (compensation details)
-	DateOfJoining  string `json:"dateOfJoining"`  // Date when the employee will start
-	DateOfRelease  string `json:"dateOfRelease"`  // Date of offer letter release
-	Name           string `json:"name"`           // Name of the offer recipient
-	Email          string `json:"email"`          // Email of the offer recipient
-	CompanyName    string `json:"companyName"`    // Name of the company making the offer
+	OfferId       string `json:"offerId"`                                   // Unique identifier for the offer
+	AssetType     string `json:"assetType"`                                 // Type of asset (e.g., "OfferLetter")
+	Ctc           *Ctc   `json:"ctc"`                                       // Cost to Company (compensation details)
+	DateOfJoining string `json:"dateOfJoining"`                             // Date when the employee will start
+	DateOfRelease string `json:"dateOfRelease"`                             // Date of offer letter release
+	Name          string `json:"name"`                                      // Name of the offer recipient
+	Email         string `json:"email"`                                     // Email of the offer recipient
+	CompanyName   string `json:"companyName"`                               // Name of the company making the offer
+	StudentId     string `json:"studentId,omitempty" metadata:",optional"`  // Student the offer is addressed to, empty on legacy offers
+	ResultId      string `json:"resultId,omitempty" metadata:",optional"`   // Result that justified the offer
+	CompanyMSP    string `json:"companyMsp,omitempty" metadata:",optional"` // MSP of the company that issued the offer
+	IssuedBy      string `json:"issuedBy,omitempty" metadata:",optional"`   // Client identity that issued the offer
+	IssuedAt      string `json:"issuedAt,omitempty" metadata:",optional"`   // Transaction timestamp of issue (RFC3339)
+	ValidUntil    string `json:"validUntil,omitempty" metadata:",optional"` // Deadline for the student to respond (RFC3339)
+	Status        string `json:"status"`                                    // Lifecycle status, e.g. ISSUED or ACCEPTED
+	Revision      int    `json:"revision,omitempty" metadata:",optional"`   // Accepted negotiation revision the terms come from
+	Nonce         string `json:"nonce,omitempty" metadata:",optional"`      // Random hex nonce salting the hashes of the terms, empty on legacy offers
+	PolicyId      string `json:"policyId,omitempty" metadata:",optional"`   // Eligibility policy candidates are matched against
+	Purged        bool   `json:"purged,omitempty" metadata:",optional"`     // Set by queries when the terms were purged from the collection
+	Warning       string `json:"warning,omitempty" metadata:",optional"`    // Explanation accompanying a purged offer
 }
 
-// StudentResult represents the structure of a student's result
-type StudentResult struct {
-    StudentID string `json:"studentId"` // Student ID
-    Percentage string `json:"percentage"` // Percentage achieved
-    Status     string `json:"status"`     // Status (e.g., "Pass", "Fail")
+// Minimum percentage a result needs for VerifyStudentResult to deem the student eligible
+const eligibilityPercentage float64 = 60
+
+// ResultCheck is the verification outcome of one of the student's results
+type ResultCheck struct {
+	ResultId   string  `json:"resultId"`                                 // Result that was checked
+	IssuerMSP  string  `json:"issuerMsp"`                                // Institution that issued the result
+	Percentage float64 `json:"percentage"`                               // Percentage recorded on the result
+	Eligible   bool    `json:"eligible"`                                 // Whether this result alone makes the student eligible
+	Reason     string  `json:"reason,omitempty" metadata:",optional"` // Why the result does not count, if it does not
 }
 
+// EligibilityVerdict is the structured answer of VerifyStudentResult
+type EligibilityVerdict struct {
+	StudentId     string         `json:"studentId"`                                 // Student that was verified
+	Eligible      bool           `json:"eligible"`                                  // Whether any result meets the minimum percentage
+	MinPercentage float64        `json:"minPercentage"`                             // Minimum percentage applied
+	ResultId      string         `json:"resultId,omitempty" metadata:",optional"`   // Best counting result, if any
+	IssuerMSP     string         `json:"issuerMsp,omitempty" metadata:",optional"`  // Issuer of the best counting result
+	IssuerName    string         `json:"issuerName,omitempty" metadata:",optional"` // Name of that issuer at the time of issue
+	Percentage    float64        `json:"percentage"`                                // Percentage of the best counting result
+	Reason        string         `json:"reason"`                                    // Short explanation of the verdict
+	Results       []*ResultCheck `json:"results"`                                   // Every result considered
+}
 
-// Collection name for private data storage
+// Shared collection that held every company's offers before they moved to per-company
+// collections; only MigrateOffers still reads it
 const collectionName string = "Offers"
 
-// OfferExists checks if an offer with the given ID exists in the private data collection
+// OfferExists checks if an offer with the given ID has a record on the ledger
 func (o *OfferContract) OfferExists(ctx contractapi.TransactionContextInterface, offerId string) (bool, error) {
-	// Retrieve the private data hash to check existence
-	data, err := ctx.GetStub().GetPrivateDataHash(collectionName, offerId)
+	_, err := authorize(ctx, ActionOfferRead)
+	if err != nil {
+		return false, err
+	}
 
+	// The public record is written alongside the private terms
+	record, err := getOfferRecord(ctx, offerId)
 	if err != nil {
-		return false, fmt.Errorf("could not fetch the private data hash. %s", err)
+		return false, err
 	}
 
-	return data != nil, nil
+	return record != nil, nil
 }
 
-// CreateOffer adds a new offer letter to the private data collection
-func (o *OfferContract) CreateOffer(ctx contractapi.TransactionContextInterface, offerId string) (string, error) {
-	// Verify client organization identity
-	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+// CreateOffer adds a new offer letter for a student to the private data collection. resultId is
+// optional and names the result that justified the offer; it must be one the recruiter can read.
+func (o *OfferContract) CreateOffer(ctx contractapi.TransactionContextInterface, offerId string, studentId string, resultId string) (string, error) {
+	// Restrict offer creation to company recruiters
+	_, err := authorize(ctx, ActionOfferCreate)
 	if err != nil {
-		return "", fmt.Errorf("could not fetch client identity. %s", err)
+		return "", err
 	}
 
-	// Restrict offer creation to CompanyMSP
-	if clientOrgID == "CompanyMSP" {
-		// Check if offer already exists
-		exists, err := o.OfferExists(ctx, offerId)
-		if err != nil {
-			return "", fmt.Errorf("could not read from world state. %s", err)
-		} else if exists {
-			return "", fmt.Errorf("the asset %s already exists", offerId)
-		}
+	// Check if offer already exists
+	exists, err := o.OfferExists(ctx, offerId)
+	if err != nil {
+		return "", err
+	} else if exists {
+		return "", newChaincodeError(CodeConflict, "the asset %s already exists", offerId)
+	}
 
-		var offer Offer
+	// Commitments outlive deleted offers, so their IDs cannot be reused
+	commitment, err := getOfferCommitment(ctx, offerId)
+	if err != nil {
+		return "", err
+	} else if commitment != nil {
+		return "", newChaincodeError(CodeConflict, "offer ID %s was already used by %s", offerId, commitment.CompanyMSP)
+	}
 
-		// Retrieve transient data (sensitive information)
-		transientData, err := ctx.GetStub().GetTransient()
+	// The offer must name its student, and any supporting result must be theirs and still valid
+	if studentId == "" {
+		return "", newChaincodeError(CodeInvalidArgument, "an offer must name the student it is addressed to")
+	}
+	if resultId != "" {
+		result, err := getResult(ctx, resultId)
 		if err != nil {
-			return "", fmt.Errorf("could not fetch transient data. %s", err)
+			return "", err
 		}
-
-		// Validate transient data is not empty
-		if len(transientData) == 0 {
-			return "", fmt.Errorf("please provide the private data of ctc, fixed, variable, date of joining, date of release, name of person, address of person, contact of person, company name")
+		err = authorizeResultRead(ctx, result)
+		if err != nil {
+			return "", err
 		}
-
-		// Extract and validate each piece of transient data
-		ctc, exists := transientData["ctc"]
-		if !exists {
-			return "", fmt.Errorf("the ctc was not specified in transient data. Please try again")
+		if result.StudentId != studentId {
+			return "", newChaincodeError(CodeForbidden, "result %s does not belong to student %s", resultId, studentId)
 		}
-		offer.Ctc = string(ctc)
-
-		dateOfJoining, exists := transientData["dateOfJoining"]
-		if !exists {
-			return "", fmt.Errorf("the date of joining was not specified in transient data. Please try again")
+		if result.Revoked {
+			return "", newChaincodeError(CodeConflict, "result %s has been revoked and cannot back an offer", resultId)
 		}
-		offer.DateOfJoining = string(dateOfJoining)
+	}
 
-		dateOfRelease, exists := transientData["dateOfRelease"]
-		if !exists {
-			return "", fmt.Errorf("the date of release was not specified in transient data. Please try again")
-		}
-		offer.DateOfRelease = string(dateOfRelease)
+	// Retrieve transient data (sensitive information)
+	transientData, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch transient data. %s", err)
+	}
 
-		name, exists := transientData["name"]
-		if !exists {
-			return "", fmt.Errorf("the name was not specified in transient data. Please try again")
-		}
-		offer.Name = string(name)
+	issuedAt, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
 
-		email, exists := transientData["email"]
-		if !exists {
-			return "", fmt.Errorf("the email was not specified in transient data. Please try again")
-		}
-		offer.Email = string(email)
+	// Validate every transient field, reporting all rejected fields at once
+	var offer Offer
+	err = parseOfferTransient(transientData, issuedAt, &offer)
+	if err != nil {
+		return "", err
+	}
 
-		companyName, exists := transientData["companyName"]
-		if !exists {
-			return "", fmt.Errorf("the companyName was not specified in transient data. Please try again")
-		}
-		offer.CompanyName = string(companyName)
+	// Set additional offer details
+	offer.AssetType = "OfferLetter"
+	offer.OfferId = offerId
+	offer.StudentId = studentId
+	offer.ResultId = resultId
+	offer.Status = OfferIssued
+	offer.IssuedAt = issuedAt.Format(time.RFC3339)
 
-		// Set additional offer details
-		offer.AssetType = "OfferLetter"
-		offer.OfferId = offerId
+	offer.CompanyMSP, err = ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	offer.IssuedBy, err = ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
 
-		// Serialize and store offer in private data collection
-		bytes, _ := json.Marshal(offer)
-		err = ctx.GetStub().PutPrivateData(collectionName, offerId, bytes)
-		if err != nil {
-			return "", fmt.Errorf("could not able to write the data")
-		}
-		return fmt.Sprintf("offer with id %v added successfully", offerId), nil
-	} else {
-		return fmt.Sprintf("offer cannot be created by organisation with MSPID %v ", clientOrgID), nil
+	// Serialize and store offer in private data collection, with its public commitment
+	err = putOffer(ctx, &offer)
+	if err != nil {
+		return "", err
 	}
+	err = putOfferCommitment(ctx, &offer)
+	if err != nil {
+		return "", err
+	}
+	err = recordOfferHistory(ctx, &offer, "", OfferIssued, "")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("offer with id %v added successfully", offerId), nil
 }
 
 // ReadOffer retrieves an offer letter from the private data collection
 func (o *OfferContract) ReadOffer(ctx contractapi.TransactionContextInterface, offerId string) (*Offer, error) {
-	// Verify client organization identity
-	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	// Allow reading for the named student and the recruiters of the issuing company
+	return readAuthorizedOffer(ctx, ActionOfferRead, offerId)
+}
+
+// GetOffersByStudent lists the offers addressed to a student. Recruiters see their own company's
+// offers only, and students may only list their own.
+func (o *OfferContract) GetOffersByStudent(ctx contractapi.TransactionContextInterface, studentId string) ([]*Offer, error) {
+	role, err := authorize(ctx, ActionOfferRead)
 	if err != nil {
-		return nil, fmt.Errorf("could not read the client identity. %s", err)
+		return nil, err
 	}
 
-	// Allow reading for CompanyMSP and StudentMSP
-	if clientOrgID == "CompanyMSP" || clientOrgID == "StudentMSP" {
-		// Check if offer exists
-		exists, err := o.OfferExists(ctx, offerId)
-		if err != nil {
-			return nil, fmt.Errorf("could not read from world state. %s", err)
-		} else if !exists {
-			return nil, fmt.Errorf("the asset %s does not exist", offerId)
-		}
-
-		// Retrieve private data
-		bytes, err := ctx.GetStub().GetPrivateData(collectionName, offerId)
-		if err != nil {
-			return nil, fmt.Errorf("could not get the private data. %s", err)
-		}
-		var offer Offer
-
-		// Deserialize offer data
-		err = json.Unmarshal(bytes, &offer)
-		if err != nil {
-			return nil, fmt.Errorf("could not unmarshal private data collection data to type Offer")
-		}
+	queryString := fmt.Sprintf(`{"selector":{"assetType":"OfferLetter","studentId":%q}}`, studentId)
+	return queryOffers(ctx, role, queryString)
+}
 
-		return &offer, nil
+// GetOffersByResult lists the offers that were justified by a result, restricted like GetOffersByStudent
+func (o *OfferContract) GetOffersByResult(ctx contractapi.TransactionContextInterface, resultId string) ([]*Offer, error) {
+	role, err := authorize(ctx, ActionOfferRead)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("%v not allowed to read.", clientOrgID)
+	queryString := fmt.Sprintf(`{"selector":{"assetType":"OfferLetter","resultId":%q}}`, resultId)
+	return queryOffers(ctx, role, queryString)
 }
 
 // DeleteOffer removes an offer letter from the private data collection
 func (o *OfferContract) DeleteOffer(ctx contractapi.TransactionContextInterface, offerId string) error {
-	// Verify client organization identity
-	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	// Restrict deletion to company recruiters
+	_, err := authorize(ctx, ActionOfferDelete)
 	if err != nil {
-		return fmt.Errorf("could not read the client identity. %s", err)
+		return err
 	}
 
-	// Restrict deletion to CompanyMSP
-	if clientOrgID == "CompanyMSP" {
-		// Check if offer exists
-		exists, err := o.OfferExists(ctx, offerId)
-		if err != nil {
-			return fmt.Errorf("could not read from world state. %s", err)
-		} else if !exists {
-			return fmt.Errorf("the offer %s does not exist", offerId)
-		}
-
-		// Delete offer from private data collection
-		return ctx.GetStub().DelPrivateData(collectionName, offerId)
-	} else {
-		return fmt.Errorf("organisation with %v cannot delete the offer", clientOrgID)
+	// Only the issuing company may delete its offer
+	record, err := getOfferRecord(ctx, offerId)
+	if err != nil {
+		return fmt.Errorf("could not read from world state. %s", err)
+	} else if record == nil {
+		return newChaincodeError(CodeNotFound, "the offer %s does not exist", offerId)
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("could not fetch client identity: %s", err)
 	}
+	if record.CompanyMSP != clientOrgID {
+		return newChaincodeError(CodeForbidden, "offer %s was issued by %s and cannot be deleted by %v", offerId, record.CompanyMSP, clientOrgID)
+	}
+
+	// Delete offer from private data collection and its public record
+	return deleteOffer(ctx, record)
 }
 
-// GetAllOffers retrieves all offers of a specific asset type
+// GetAllOffers retrieves the offers readable by the caller: a recruiter's own company's, or all for students
 func (o *OfferContract) GetAllOffers(ctx contractapi.TransactionContextInterface) ([]*Offer, error) {
-	// Query string to select offers by asset type
-	queryString := `{"selector":{"assetType":"OfferLetter"}}`
+	return o.getOffersInRange(ctx, "", "")
+}
+
+// GetOffersByRange retrieves readable offers whose IDs lie in [startKey, endKey); an empty bound is open
+func (o *OfferContract) GetOffersByRange(ctx contractapi.TransactionContextInterface, startKey string, endKey string) ([]*Offer, error) {
+	return o.getOffersInRange(ctx, startKey, endKey)
+}
+
+// getOffersInRange reads the terms of the readable offers with IDs in [startKey, endKey)
+func (o *OfferContract) getOffersInRange(ctx contractapi.TransactionContextInterface, startKey string, endKey string) ([]*Offer, error) {
+	role, err := authorize(ctx, ActionOfferRead)
+	if err != nil {
+		return nil, err
+	}
 
-	// Execute query on private data collection
-	resultsIterator, err := ctx.GetStub().GetPrivateDataQueryResult(collectionName, queryString)
+	records, err := readableOfferRecords(ctx, role)
 	if err != nil {
-		return nil, fmt.Errorf("could not fetch the query result. %s", err)
+		return nil, err
 	}
-	defer resultsIterator.Close()
 
-	// Process and return results
-	return OfferResultIteratorFunction(resultsIterator)
+	var offers []*Offer
+	for _, record := range records {
+		if (startKey != "" && record.OfferId < startKey) || (endKey != "" && record.OfferId >= endKey) {
+			continue
+		}
+		// Purged offers are listed with a warning instead of failing the query
+		offer, err := getOfferOrPurged(ctx, record)
+		if err != nil {
+			return nil, err
+		}
+		// Students only see the offers addressed to them
+		if authorizeOfferParty(ctx, role, offer) != nil {
+			continue
+		}
+		offers = append(offers, offer)
+	}
+
+	return offers, nil
 }
 
-// GetOffersByRange retrieves offers within a specified key range
-func (o *OfferContract) GetOffersByRange(ctx contractapi.TransactionContextInterface, startKey string, endKey string) ([]*Offer, error) {
-	// Retrieve offers from private data collection within the specified key range
-	resultsIterator, err := ctx.GetStub().GetPrivateDataByRange(collectionName, startKey, endKey)
+// MigrateOffers moves every offer, with its history, from the shared Offers collection to its
+// issuing company's collection and writes their public records and commitments. Offers written
+// before issuers were recorded are assigned to legacyOwner, which consortium governors choose
+// explicitly; they stay in the shared collection when it is empty.
+func (o *OfferContract) MigrateOffers(ctx contractapi.TransactionContextInterface, legacyOwner string) (string, error) {
+	_, err := authorize(ctx, ActionOfferMigrate)
+	if err != nil {
+		return "", err
+	}
+	if legacyOwner != "" && !isOfferIssuer(legacyOwner) {
+		return "", newChaincodeError(CodeInvalidArgument, "%s is not a company MSP that issues offers", legacyOwner)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetPrivateDataByRange(collectionName, "", "")
+	if err != nil {
+		return "", fmt.Errorf("could not fetch the private data by range. %s", err)
+	}
+	legacyOffers, err := OfferResultIteratorFunction(resultsIterator)
+	resultsIterator.Close()
 	if err != nil {
-		return nil, fmt.Errorf("could not fetch the private data by range. %s", err)
+		return "", err
 	}
-	defer resultsIterator.Close()
 
-	// Process and return results
-	return OfferResultIteratorFunction(resultsIterator)
+	migratedAt, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	migrated := 0
+	for _, offer := range legacyOffers {
+		if offer.CompanyMSP == "" {
+			offer.CompanyMSP = legacyOwner
+		}
+		if offer.CompanyMSP == "" {
+			continue
+		}
+		// Offers issued before issue times were recorded are committed as of the migration
+		if offer.IssuedAt == "" {
+			offer.IssuedAt = migratedAt
+		}
+		// Offers issued before nonces were recorded are salted from the migration secret
+		if offer.Nonce == "" {
+			offer.Nonce, err = migratedOfferNonce(ctx, offer.OfferId)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		err = putOffer(ctx, offer)
+		if err != nil {
+			return "", err
+		}
+		err = putOfferCommitment(ctx, offer)
+		if err != nil {
+			return "", err
+		}
+		err = ctx.GetStub().DelPrivateData(collectionName, offer.OfferId)
+		if err != nil {
+			return "", fmt.Errorf("could not delete the private data. %s", err)
+		}
+
+		historyIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(collectionName, offerHistoryObjectType, []string{offer.OfferId})
+		if err != nil {
+			return "", fmt.Errorf("could not fetch offer history: %v", err)
+		}
+		for historyIterator.HasNext() {
+			entry, err := historyIterator.Next()
+			if err != nil {
+				historyIterator.Close()
+				return "", fmt.Errorf("could not fetch offer history entry: %v", err)
+			}
+			err = ctx.GetStub().PutPrivateData(offerCollectionName(offer.CompanyMSP), entry.Key, entry.Value)
+			if err == nil {
+				err = ctx.GetStub().DelPrivateData(collectionName, entry.Key)
+			}
+			if err != nil {
+				historyIterator.Close()
+				return "", fmt.Errorf("could not move offer history entry: %v", err)
+			}
+		}
+		historyIterator.Close()
+		migrated++
+	}
+
+	return fmt.Sprintf("Migrated %d offers to their companies' collections", migrated), nil
 }
 
 // OfferResultIteratorFunction is a helper function to process query iterators and convert results
@@ -240,6 +381,9 @@ func OfferResultIteratorFunction(resultsIterator shim.StateQueryIteratorInterfac
 		if err != nil {
 			return nil, fmt.Errorf("could not unmarshal the data. %s", err)
 		}
+		if offer.Status == "" {
+			offer.Status = OfferIssued
+		}
 
 		// Append the offer to the list
 		offers = append(offers, &offer)
@@ -249,32 +393,82 @@ func OfferResultIteratorFunction(resultsIterator shim.StateQueryIteratorInterfac
 }
 
 
-// Corrected VerifyStudentResult function
-func (o *OfferContract) VerifyStudentResult(ctx contractapi.TransactionContextInterface, studentId string) (string, error) {
-    // Query ResultContract to get the student's result
-    response := ctx.GetStub().InvokeChaincode("ResultContract", [][]byte{[]byte("GetStudentResult"), []byte(studentId)}, "mychannel")
-
-    if response.Status != shim.OK {
-        return "", fmt.Errorf("failed to query the ResultContract: %v", response.Message)
-    }
-
-    // Parse the result (assuming the result is returned in JSON format)
-    var studentResult StudentResult
-    err := json.Unmarshal(response.Payload, &studentResult)
-    if err != nil {
-        return "", fmt.Errorf("failed to parse student result: %v", err)
-    }
-
-    // Convert the percentage from string to float64
-    percentage, err := strconv.ParseFloat(studentResult.Percentage, 64)
-    if err != nil {
-        return "", fmt.Errorf("failed to convert percentage to float64: %v", err)
-    }
-
-    // Logic to verify the result (check if percentage >= 60)
-    if percentage >= 60 {
-        return fmt.Sprintf("Student %s is eligible with %.2f%%", studentId, percentage), nil
-    } else {
-        return fmt.Sprintf("Student %s is not eligible, only %.2f%%", studentId, percentage), nil
-    }
+// VerifyStudentResult checks the student's results readable by the caller and returns a verdict based
+// on the best one that is neither revoked nor issued by an institution that is no longer active
+func (o *OfferContract) VerifyStudentResult(ctx contractapi.TransactionContextInterface, studentId string) (*EligibilityVerdict, error) {
+	_, err := authorize(ctx, ActionOfferVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := getResultsByStudent(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	verdict := &EligibilityVerdict{
+		StudentId:     studentId,
+		MinPercentage: eligibilityPercentage,
+		Results:       []*ResultCheck{},
+	}
+
+	// Companies only see the results the student has granted them consent to
+	readable, err := readableResultsWithMarks(ctx, results)
+	if err != nil {
+		return nil, err
+	}
+	if len(readable) == 0 {
+		verdict.Reason = "no results of the student are readable under an active consent"
+		return verdict, nil
+	}
+
+	var best *Result
+	for _, result := range readable {
+		check := &ResultCheck{
+			ResultId:   result.ResultId,
+			IssuerMSP:  result.IssuerMSP,
+			Percentage: result.Percentage,
+		}
+		verdict.Results = append(verdict.Results, check)
+
+		active, err := isActiveInstitution(ctx, result.IssuerMSP)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case result.Revoked:
+			check.Reason = "result has been revoked"
+			continue
+		case !active:
+			check.Reason = "issuer is not an active institution"
+			continue
+		case result.Percentage < eligibilityPercentage:
+			check.Reason = fmt.Sprintf("percentage below the minimum of %.2f%%", eligibilityPercentage)
+		default:
+			check.Eligible = true
+		}
+
+		// The best valid result is the one with the highest percentage
+		if best == nil || result.Percentage > best.Percentage {
+			best = result
+		}
+	}
+
+	if best == nil {
+		verdict.Reason = "none of the student's results is valid"
+		return verdict, nil
+	}
+
+	verdict.ResultId = best.ResultId
+	verdict.IssuerMSP = best.IssuerMSP
+	verdict.IssuerName = best.IssuerName
+	verdict.Percentage = best.Percentage
+	verdict.Eligible = best.Percentage >= eligibilityPercentage
+	if verdict.Eligible {
+		verdict.Reason = fmt.Sprintf("result %s meets the minimum of %.2f%%", best.ResultId, eligibilityPercentage)
+	} else {
+		verdict.Reason = fmt.Sprintf("best valid result %s is below the minimum of %.2f%%", best.ResultId, eligibilityPercentage)
+	}
+
+	return verdict, nil
 }
@@ -0,0 +1,726 @@
+package contracts
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// VerificationContract runs background verification requests from companies to the issuing university
+type VerificationContract struct {
+	contractapi.Contract
+}
+
+// Object type of the composite key (requestId) for verification requests
+const verificationObjectType string = "verificationRequest"
+
+// Verification request status values
+const (
+	VerificationPending   string = "PENDING"
+	VerificationApproved  string = "APPROVED"
+	VerificationRejected  string = "REJECTED"
+	VerificationCancelled string = "CANCELLED"
+)
+
+// Time the issuing university has to decide on a request
+const verificationSLA = 5 * 24 * time.Hour
+
+// VerificationRequest is a company's request to the issuing university to confirm a student's result
+type VerificationRequest struct {
+	AssetType      string               `json:"assetType"`                                  // Asset type ("VerificationRequest")
+	RequestId      string               `json:"requestId"`                                  // Unique identifier for the request
+	CompanyMSP     string               `json:"companyMsp"`                                 // MSP of the requesting company
+	RequestedBy    string               `json:"requestedBy"`                                // Company client identity that opened the request
+	StudentId      string               `json:"studentId"`                                  // Student being verified
+	ResultId       string               `json:"resultId"`                                   // Result to verify
+	IssuerMSP      string               `json:"issuerMsp"`                                  // Institution that must decide
+	ConsentGrantId string               `json:"consentGrantId"`                             // Student consent the request relies on
+	Purpose        string               `json:"purpose,omitempty" metadata:",optional"`     // Why the company asks, e.g. "pre-joining check"
+	Status         string               `json:"status"`                                     // PENDING, APPROVED, REJECTED or CANCELLED
+	RequestedAt    string               `json:"requestedAt"`                                // Transaction timestamp of the request (RFC3339)
+	DueBy          string               `json:"dueBy"`                                      // SLA deadline for the decision (RFC3339)
+	ClosedAt       string               `json:"closedAt,omitempty" metadata:",optional"`    // When the request was decided or cancelled (RFC3339)
+	ClosedBy       string               `json:"closedBy,omitempty" metadata:",optional"`    // Client identity that decided or cancelled it
+	Comments       string               `json:"comments,omitempty" metadata:",optional"`    // Comments of the university, or the cancellation reason
+	SlaBreached    bool                 `json:"slaBreached,omitempty" metadata:",optional"` // Whether the decision came after dueBy
+	Overdue        bool                 `json:"overdue,omitempty" metadata:",optional"`     // Set by queries on pending requests past dueBy
+	Outcome        *VerificationOutcome `json:"outcome,omitempty" metadata:",optional"`     // Signed outcome, once decided
+}
+
+// VerificationOutcome is the university's signed answer to a verification request. Anyone holding
+// it can check Signature against Digest with the public key of SignerCertificate. The marks it
+// confirms are not part of it: an approval signs their salted commitment, and the requesting
+// company obtains the marks and salt with GetVerifiedMarks.
+type VerificationOutcome struct {
+	RequestId         string `json:"requestId"`                                        // Request answered
+	CompanyMSP        string `json:"companyMsp"`                                       // Requesting company
+	StudentId         string `json:"studentId"`                                        // Student verified
+	ResultId          string `json:"resultId"`                                         // Result verified
+	IssuerMSP         string `json:"issuerMsp"`                                        // Institution answering
+	Decision          string `json:"decision"`                                         // APPROVED or REJECTED
+	MarksCommitment   string `json:"marksCommitment,omitempty" metadata:",optional"`   // Salted hash of the marks confirmed by an approval
+	Revoked           bool   `json:"revoked"`                                          // Whether the result was revoked at decision time
+	Comments          string `json:"comments"`                                         // Comments of the university
+	Digest            string `json:"digest"`                                           // Hex SHA-256 of the fields above, the value that is signed
+	Signature         string `json:"signature,omitempty" metadata:",optional"`         // Base64 ASN.1 ECDSA signature of the digest
+	SignerCertificate string `json:"signerCertificate,omitempty" metadata:",optional"` // PEM certificate of the signing registrar
+	DecidedAt         string `json:"decidedAt,omitempty" metadata:",optional"`         // Transaction timestamp of the decision (RFC3339)
+	TxId              string `json:"txId,omitempty" metadata:",optional"`              // Transaction that recorded the decision
+}
+
+// verificationStatement holds the signed fields of an outcome, in a fixed field order. The decision
+// time is left out so that the registrar can sign before submitting.
+type verificationStatement struct {
+	RequestId       string `json:"requestId"`
+	CompanyMSP      string `json:"companyMsp"`
+	StudentId       string `json:"studentId"`
+	ResultId        string `json:"resultId"`
+	IssuerMSP       string `json:"issuerMsp"`
+	Decision        string `json:"decision"`
+	MarksCommitment string `json:"marksCommitment"`
+	Revoked         bool   `json:"revoked"`
+	Comments        string `json:"comments"`
+}
+
+// Object type of the composite key (requestId) for the marks confirmed by approved requests, kept
+// in the marks collection of the issuer
+const verifiedMarksObjectType string = "verifiedMarks"
+
+// VerifiedMarks are the marks an approval confirmed. MarksCommitment of the outcome is
+// commitField("marks", Percentage+"|"+ResultStatus, Salt), the percentage written in its shortest
+// decimal form.
+type VerifiedMarks struct {
+	RequestId    string  `json:"requestId"`    // Approved request
+	ResultId     string  `json:"resultId"`     // Result verified
+	Percentage   float64 `json:"percentage"`   // Percentage on record at decision time
+	ResultStatus string  `json:"resultStatus"` // Pass/Fail status on record at decision time
+	Salt         string  `json:"salt"`         // Salt of the commitment
+}
+
+// VerificationEvent is the public chaincode event of a step of a request. It carries no result data.
+type VerificationEvent struct {
+	RequestId  string `json:"requestId"`  // Request concerned
+	Status     string `json:"status"`     // New status
+	CompanyMSP string `json:"companyMsp"` // Requesting company
+	IssuerMSP  string `json:"issuerMsp"`  // Deciding institution
+	DueBy      string `json:"dueBy"`      // SLA deadline (RFC3339)
+	Timestamp  string `json:"timestamp"`  // Transaction timestamp (RFC3339)
+	TxId       string `json:"txId"`       // Transaction ID
+}
+
+// RequestVerification opens a verification request for a result. The calling recruiter must hold
+// an active consent grant from the student covering the result.
+func (v *VerificationContract) RequestVerification(ctx contractapi.TransactionContextInterface, requestId string, resultId string, purpose string) (string, error) {
+	if strings.TrimSpace(requestId) == "" {
+		return "", newChaincodeError(CodeInvalidArgument, "requestId cannot be empty")
+	}
+
+	_, err := authorize(ctx, ActionVerificationOpen)
+	if err != nil {
+		return "", err
+	}
+
+	existing, err := getVerificationRequest(ctx, requestId)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return "", newChaincodeError(CodeConflict, "verification request %s already exists", requestId)
+	}
+
+	result, err := getResult(ctx, resultId)
+	if err != nil {
+		return "", err
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	grant, err := findActiveGrant(ctx, clientOrgID, result.StudentId, resultId)
+	if err != nil {
+		return "", err
+	}
+	if grant == nil {
+		return "", newChaincodeError(CodeForbidden, "no active consent of student %s allows this identity to verify result %s", result.StudentId, resultId)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	request := VerificationRequest{
+		AssetType:      "VerificationRequest",
+		RequestId:      requestId,
+		CompanyMSP:     clientOrgID,
+		RequestedBy:    clientID,
+		StudentId:      result.StudentId,
+		ResultId:       resultId,
+		IssuerMSP:      result.IssuerMSP,
+		ConsentGrantId: grant.GrantId,
+		Purpose:        purpose,
+		Status:         VerificationPending,
+		RequestedAt:    now.Format(time.RFC3339),
+		DueBy:          now.Add(verificationSLA).Format(time.RFC3339),
+	}
+
+	err = putVerificationRequest(ctx, &request)
+	if err != nil {
+		return "", err
+	}
+	err = emitVerificationEvent(ctx, "VerificationRequested", &request)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Verification request %v opened with %v, due by %v", requestId, request.IssuerMSP, request.DueBy), nil
+}
+
+// GetVerificationStatement returns the outcome a registrar of the issuing institution is about to
+// sign, with its digest. The signature of the digest is then passed to ApproveVerification or
+// RejectVerification together with the same comments.
+func (v *VerificationContract) GetVerificationStatement(ctx contractapi.TransactionContextInterface, requestId string, decision string, comments string) (*VerificationOutcome, error) {
+	request, err := readDecidableRequest(ctx, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if decision != VerificationApproved && decision != VerificationRejected {
+		return nil, newChaincodeError(CodeInvalidArgument, "decision must be %s or %s", VerificationApproved, VerificationRejected)
+	}
+	outcome, _, err := newVerificationOutcome(ctx, request, decision, comments)
+	return outcome, err
+}
+
+// ApproveVerification confirms the result of a pending request with the registrar's signature
+// of the statement digest. The student's consent must still be active.
+func (v *VerificationContract) ApproveVerification(ctx contractapi.TransactionContextInterface, requestId string, comments string, signature string) (string, error) {
+	return decideVerification(ctx, requestId, VerificationApproved, comments, signature)
+}
+
+// RejectVerification declines a pending request, e.g. when the record does not match, with the
+// registrar's signature of the statement digest. A rejection confirms no marks, so it needs no
+// active consent.
+func (v *VerificationContract) RejectVerification(ctx contractapi.TransactionContextInterface, requestId string, comments string, signature string) (string, error) {
+	return decideVerification(ctx, requestId, VerificationRejected, comments, signature)
+}
+
+// GetVerifiedMarks hands the marks confirmed by an approved request, with the salt of their
+// commitment, to the requesting company while the student's consent is active. The marks are kept
+// in the issuer's marks collection, so the transaction runs on its member peers, and each read is
+// recorded as a consent access.
+func (v *VerificationContract) GetVerifiedMarks(ctx contractapi.TransactionContextInterface, requestId string) (*VerifiedMarks, error) {
+	_, err := authorize(ctx, ActionVerificationOpen)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := getVerificationRequest(ctx, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, newChaincodeError(CodeNotFound, "the verification request %s does not exist", requestId)
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	if request.CompanyMSP != clientOrgID {
+		return nil, newChaincodeError(CodeForbidden, "verification request %s belongs to %s and cannot be read by %v", requestId, request.CompanyMSP, clientOrgID)
+	}
+	if request.Status != VerificationApproved {
+		return nil, newChaincodeError(CodeConflict, "verification request %s is %s and confirmed no marks", requestId, request.Status)
+	}
+
+	grant, err := getConsentGrant(ctx, request.ConsentGrantId)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !grant.isActive(now) {
+		return nil, newChaincodeError(CodeForbidden, "consent %s of student %s is no longer active", grant.GrantId, request.StudentId)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(verifiedMarksObjectType, []string{requestId})
+	if err != nil {
+		return nil, fmt.Errorf("could not create verified marks key: %v", err)
+	}
+	collection := marksCollectionName(request.IssuerMSP)
+	marksBytes, err := ctx.GetStub().GetPrivateData(collection, key)
+	if err != nil {
+		return nil, fmt.Errorf("verified marks can only be read on peers of the %s collection: %v", collection, err)
+	}
+	if marksBytes == nil {
+		return nil, newChaincodeError(CodeNotFound, "marks verified by request %s are not available on this peer", requestId)
+	}
+
+	var marks VerifiedMarks
+	err = json.Unmarshal(marksBytes, &marks)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal verified marks: %v", err)
+	}
+
+	err = recordConsentEvent(ctx, request.StudentId, ConsentAccessed, grant.GrantId, request.ResultId)
+	if err != nil {
+		return nil, err
+	}
+	return &marks, nil
+}
+
+// CancelVerification lets the requesting company withdraw a pending request
+func (v *VerificationContract) CancelVerification(ctx contractapi.TransactionContextInterface, requestId string, reason string) (string, error) {
+	_, err := authorize(ctx, ActionVerificationOpen)
+	if err != nil {
+		return "", err
+	}
+
+	request, err := getVerificationRequest(ctx, requestId)
+	if err != nil {
+		return "", err
+	}
+	if request == nil {
+		return "", newChaincodeError(CodeNotFound, "the verification request %s does not exist", requestId)
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	if request.CompanyMSP != clientOrgID {
+		return "", newChaincodeError(CodeForbidden, "verification request %s belongs to %s and cannot be cancelled by %v", requestId, request.CompanyMSP, clientOrgID)
+	}
+	if request.Status != VerificationPending {
+		return "", newChaincodeError(CodeConflict, "verification request %s is %s and cannot be cancelled", requestId, request.Status)
+	}
+
+	err = closeVerificationRequest(ctx, request, VerificationCancelled, reason)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Verification request %v cancelled", requestId), nil
+}
+
+// ReadVerificationRequest retrieves a request and, once decided, its signed outcome. Companies see
+// their own requests, institutions the requests addressed to them and students those about them.
+func (v *VerificationContract) ReadVerificationRequest(ctx contractapi.TransactionContextInterface, requestId string) (*VerificationRequest, error) {
+	role, err := authorize(ctx, ActionVerificationRead)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := getVerificationRequest(ctx, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, newChaincodeError(CodeNotFound, "the verification request %s does not exist", requestId)
+	}
+	err = authorizeVerificationRead(ctx, role, request)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request.markOverdue(now)
+	return request, nil
+}
+
+// GetPendingVerifications lists the caller's pending requests, earliest deadline first, flagging
+// those past their SLA
+func (v *VerificationContract) GetPendingVerifications(ctx contractapi.TransactionContextInterface) ([]*VerificationRequest, error) {
+	role, err := authorize(ctx, ActionVerificationRead)
+	if err != nil {
+		return nil, err
+	}
+
+	var field, value string
+	switch role {
+	case RoleHR:
+		field = "companyMsp"
+		value, err = ctx.GetClientIdentity().GetMSPID()
+	case RoleStudent:
+		field = "studentId"
+		value, err = clientStudentId(ctx)
+	default:
+		field = "issuerMsp"
+		value, err = ctx.GetClientIdentity().GetMSPID()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	queryString := fmt.Sprintf(`{"selector":{"assetType":"VerificationRequest","status":%q,%q:%q}}`, VerificationPending, field, value)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch verification requests: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var requests []*VerificationRequest
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch verification request: %v", err)
+		}
+
+		var request VerificationRequest
+		err = json.Unmarshal(queryResult.Value, &request)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal verification request: %v", err)
+		}
+		request.markOverdue(now)
+		requests = append(requests, &request)
+	}
+
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].DueBy < requests[j].DueBy
+	})
+	return requests, nil
+}
+
+// markOverdue flags a pending request whose SLA deadline has passed
+func (r *VerificationRequest) markOverdue(now time.Time) {
+	dueBy, err := time.Parse(time.RFC3339, r.DueBy)
+	r.Overdue = r.Status == VerificationPending && err == nil && now.After(dueBy)
+}
+
+// decideVerification checks the registrar's signature of the statement and closes the request with the signed outcome
+func decideVerification(ctx contractapi.TransactionContextInterface, requestId string, decision string, comments string, signature string) (string, error) {
+	request, err := readDecidableRequest(ctx, requestId)
+	if err != nil {
+		return "", err
+	}
+
+	if decision == VerificationApproved {
+		grant, err := getConsentGrant(ctx, request.ConsentGrantId)
+		if err != nil {
+			return "", err
+		}
+		now, err := txTime(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !grant.isActive(now) {
+			return "", newChaincodeError(CodeConflict, "consent %s of student %s is no longer active; the request can only be rejected", grant.GrantId, request.StudentId)
+		}
+	}
+
+	outcome, marks, err := newVerificationOutcome(ctx, request, decision, comments)
+	if err != nil {
+		return "", err
+	}
+	err = verifyOutcomeSignature(ctx, outcome, signature)
+	if err != nil {
+		return "", err
+	}
+	if marks != nil {
+		err = putVerifiedMarks(ctx, request, marks)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	outcome.DecidedAt, err = txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	outcome.TxId = ctx.GetStub().GetTxID()
+	request.Outcome = outcome
+
+	err = closeVerificationRequest(ctx, request, decision, comments)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Verification request %v %s", requestId, strings.ToLower(decision)), nil
+}
+
+// readDecidableRequest loads a pending request addressed to the calling registrar's institution
+func readDecidableRequest(ctx contractapi.TransactionContextInterface, requestId string) (*VerificationRequest, error) {
+	_, err := authorize(ctx, ActionVerificationSign)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := getVerificationRequest(ctx, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, newChaincodeError(CodeNotFound, "the verification request %s does not exist", requestId)
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	if request.IssuerMSP != clientOrgID {
+		return nil, newChaincodeError(CodeForbidden, "verification request %s is addressed to %s and cannot be decided by %v", requestId, request.IssuerMSP, clientOrgID)
+	}
+	if request.Status != VerificationPending {
+		return nil, newChaincodeError(CodeConflict, "verification request %s is already %s", requestId, request.Status)
+	}
+	return request, nil
+}
+
+// newVerificationOutcome builds the unsigned outcome of a decision from the result as currently
+// recorded. Approvals also return the marks they confirm, whose salted commitment the outcome
+// carries; rejections read no marks.
+func newVerificationOutcome(ctx contractapi.TransactionContextInterface, request *VerificationRequest, decision string, comments string) (*VerificationOutcome, *VerifiedMarks, error) {
+	result, err := getResult(ctx, request.ResultId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var marks *VerifiedMarks
+	var marksCommitment string
+	if decision == VerificationApproved {
+		err = loadResultMarks(ctx, result)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The salt is derived from the result's private salt, so that it is the same when the
+		// statement is fetched and when it is submitted, and differs between requests
+		mac := hmac.New(sha256.New, []byte(result.salt))
+		mac.Write([]byte(request.RequestId))
+		marks = &VerifiedMarks{
+			RequestId:    request.RequestId,
+			ResultId:     request.ResultId,
+			Percentage:   result.Percentage,
+			ResultStatus: result.Status,
+			Salt:         hex.EncodeToString(mac.Sum(nil)),
+		}
+		marksCommitment = marks.commitment()
+	}
+
+	statement := verificationStatement{
+		RequestId:       request.RequestId,
+		CompanyMSP:      request.CompanyMSP,
+		StudentId:       request.StudentId,
+		ResultId:        request.ResultId,
+		IssuerMSP:       request.IssuerMSP,
+		Decision:        decision,
+		MarksCommitment: marksCommitment,
+		Revoked:         result.Revoked,
+		Comments:        comments,
+	}
+	statementBytes, err := json.Marshal(statement)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal verification statement: %v", err)
+	}
+	digest := sha256.Sum256(statementBytes)
+
+	return &VerificationOutcome{
+		RequestId:       statement.RequestId,
+		CompanyMSP:      statement.CompanyMSP,
+		StudentId:       statement.StudentId,
+		ResultId:        statement.ResultId,
+		IssuerMSP:       statement.IssuerMSP,
+		Decision:        statement.Decision,
+		MarksCommitment: statement.MarksCommitment,
+		Revoked:         statement.Revoked,
+		Comments:        statement.Comments,
+		Digest:          hex.EncodeToString(digest[:]),
+	}, marks, nil
+}
+
+// commitment is the salted hash of the confirmed marks signed in an approval
+func (m *VerifiedMarks) commitment() string {
+	return commitField("marks", strconv.FormatFloat(m.Percentage, 'f', -1, 64)+"|"+m.ResultStatus, m.Salt)
+}
+
+// putVerifiedMarks keeps the marks confirmed by an approval in the marks collection of the issuer
+func putVerifiedMarks(ctx contractapi.TransactionContextInterface, request *VerificationRequest, marks *VerifiedMarks) error {
+	key, err := ctx.GetStub().CreateCompositeKey(verifiedMarksObjectType, []string{request.RequestId})
+	if err != nil {
+		return fmt.Errorf("could not create verified marks key: %v", err)
+	}
+	marksBytes, err := json.Marshal(marks)
+	if err != nil {
+		return fmt.Errorf("failed to marshal verified marks: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(marksCollectionName(request.IssuerMSP), key, marksBytes)
+	if err != nil {
+		return fmt.Errorf("could not write verified marks to the private collection: %v", err)
+	}
+	return nil
+}
+
+// verifyOutcomeSignature checks a base64 ECDSA signature of the outcome digest against the caller's
+// enrolment certificate and attaches both to the outcome
+func verifyOutcomeSignature(ctx contractapi.TransactionContextInterface, outcome *VerificationOutcome, signature string) error {
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return newChaincodeError(CodeInvalidArgument, "signature must be base64 encoded: %v", err)
+	}
+	digest, err := hex.DecodeString(outcome.Digest)
+	if err != nil {
+		return fmt.Errorf("invalid outcome digest: %v", err)
+	}
+
+	certificate, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return fmt.Errorf("could not fetch client certificate: %v", err)
+	}
+	publicKey, ok := certificate.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("the client certificate does not carry an ECDSA key")
+	}
+	if !ecdsa.VerifyASN1(publicKey, digest, signatureBytes) {
+		return newChaincodeError(CodeForbidden, "signature does not match digest %s for the calling identity", outcome.Digest)
+	}
+
+	outcome.Signature = signature
+	outcome.SignerCertificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw}))
+	return nil
+}
+
+// closeVerificationRequest records the final status of a request with its SLA outcome and emits its event
+func closeVerificationRequest(ctx contractapi.TransactionContextInterface, request *VerificationRequest, status string, comments string) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	dueBy, err := time.Parse(time.RFC3339, request.DueBy)
+	if err != nil {
+		return fmt.Errorf("verification request %s has an invalid dueBy %q: %v", request.RequestId, request.DueBy, err)
+	}
+
+	request.Status = status
+	request.ClosedAt = now.Format(time.RFC3339)
+	request.ClosedBy = clientID
+	request.Comments = comments
+	request.SlaBreached = status != VerificationCancelled && now.After(dueBy)
+
+	err = putVerificationRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	eventName := "VerificationCancelled"
+	switch status {
+	case VerificationApproved:
+		eventName = "VerificationApproved"
+	case VerificationRejected:
+		eventName = "VerificationRejected"
+	}
+	return emitVerificationEvent(ctx, eventName, request)
+}
+
+// authorizeVerificationRead restricts a request to its company, its institution and its student
+func authorizeVerificationRead(ctx contractapi.TransactionContextInterface, role string, request *VerificationRequest) error {
+	if role == RoleStudent {
+		studentId, err := clientStudentId(ctx)
+		if err != nil {
+			return err
+		}
+		if studentId != request.StudentId {
+			return newChaincodeError(CodeForbidden, "student %s cannot view verification request %s", studentId, request.RequestId)
+		}
+		return nil
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("could not fetch client identity: %s", err)
+	}
+	if clientOrgID != request.CompanyMSP && clientOrgID != request.IssuerMSP {
+		return newChaincodeError(CodeForbidden, "verification request %s cannot be viewed by %v", request.RequestId, clientOrgID)
+	}
+	return nil
+}
+
+// getVerificationRequest reads a request, returning nil if it does not exist
+func getVerificationRequest(ctx contractapi.TransactionContextInterface, requestId string) (*VerificationRequest, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(verificationObjectType, []string{requestId})
+	if err != nil {
+		return nil, fmt.Errorf("could not create verification key: %v", err)
+	}
+
+	requestBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if requestBytes == nil {
+		return nil, nil
+	}
+
+	var request VerificationRequest
+	err = json.Unmarshal(requestBytes, &request)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal verification request: %v", err)
+	}
+	return &request, nil
+}
+
+// putVerificationRequest writes a request to the world state
+func putVerificationRequest(ctx contractapi.TransactionContextInterface, request *VerificationRequest) error {
+	key, err := ctx.GetStub().CreateCompositeKey(verificationObjectType, []string{request.RequestId})
+	if err != nil {
+		return fmt.Errorf("could not create verification key: %v", err)
+	}
+
+	// Overdue is only computed for query answers
+	request.Overdue = false
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal verification request: %v", err)
+	}
+	err = ctx.GetStub().PutState(key, requestBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store verification request in world state: %v", err)
+	}
+	return nil
+}
+
+// emitVerificationEvent announces a step of a request under name, e.g. "VerificationApproved"
+func emitVerificationEvent(ctx contractapi.TransactionContextInterface, name string, request *VerificationRequest) error {
+	timestamp, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	eventBytes, err := json.Marshal(VerificationEvent{
+		RequestId:  request.RequestId,
+		Status:     request.Status,
+		CompanyMSP: request.CompanyMSP,
+		IssuerMSP:  request.IssuerMSP,
+		DueBy:      request.DueBy,
+		Timestamp:  timestamp,
+		TxId:       ctx.GetStub().GetTxID(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal verification event: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, eventBytes)
+	if err != nil {
+		return fmt.Errorf("failed to set verification event: %v", err)
+	}
+	return nil
+}
@@ -10,8 +10,11 @@ import (
 func main() {
 	resultsContract := new(contracts.ResultContract)
 	offerContract := new(contracts.OfferContract)
+	consentContract := new(contracts.ConsentContract)
+	institutionRegistryContract := new(contracts.InstitutionRegistryContract)
+	verificationContract := new(contracts.VerificationContract)
 
-	chaincode, err := contractapi.NewChaincode(resultsContract, offerContract)
+	chaincode, err := contractapi.NewChaincode(resultsContract, offerContract, consentContract, institutionRegistryContract, verificationContract)
 
 	if err != nil {
 		log.Panicf("Could not create chaincode : %v", err)